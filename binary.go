@@ -0,0 +1,440 @@
+package match
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"unicode/utf8"
+)
+
+// binaryMagic identifies an encoded matcher
+const binaryMagic = "mtch"
+
+// binaryVersion has to be increased every time the layout of the encoded
+// prepared list changes, older or newer encodings get rejected on load
+const binaryVersion byte = 11
+
+var (
+	// ErrInvalidFormat is returned if the data is not an encoded matcher
+	ErrInvalidFormat = errors.New("invalid format: data is not an encoded matcher")
+	// ErrVersionMismatch is returned if the data was encoded by an incompatible version of the library
+	ErrVersionMismatch = errors.New("version mismatch: matcher was encoded by an incompatible version")
+	// ErrChecksum is returned if the checksum of the encoded matcher does not match its content
+	ErrChecksum = errors.New("checksum mismatch: encoded matcher is corrupted")
+	// ErrNotSerializable is returned if a matcher can not be encoded
+	ErrNotSerializable = errors.New("matcher is not serializable")
+)
+
+const (
+	flagAdvancedPattern byte = 1 << iota
+	flagSegments
+	flagOptionalQuestion
+)
+
+const (
+	segmentKindRepeat byte = iota + 1
+	segmentKindRegexp
+	segmentKindRange
+	segmentKindPlaceholder
+	segmentKindCapture
+	segmentKindPredicate
+)
+
+// Marshal encodes a matcher returned by Compile into the versioned binary format
+func Marshal(m Matcher) ([]byte, error) {
+	bm, ok := m.(matcher)
+	if !ok {
+		return nil, ErrNotSerializable
+	}
+	return bm.MarshalBinary()
+}
+
+// Unmarshal decodes a matcher previously encoded with Marshal.
+// It skips parsing and the cartesian expansion, the stored matcher is used as is.
+// Predicates are stored by their name and get resolved from the registry of the options
+func Unmarshal(data []byte, opts ...Option) (Matcher, error) {
+	m := matcher{}
+	if err := m.unmarshal(data, newConfig(opts).registry); err != nil {
+		return matcher{}, err
+	}
+	return m, nil
+}
+
+// Write encodes a matcher returned by Compile and writes it to w
+func Write(w io.Writer, m Matcher) error {
+	data, err := Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Read reads all data from r and decodes it as a matcher
+func Read(r io.Reader, opts ...Option) (Matcher, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return matcher{}, err
+	}
+	return Unmarshal(data, opts...)
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface
+//
+// # The layout looks like the following:
+//
+// magic | version | count | count * (prepared | exclude count | exclude count * prepared) | crc32
+//
+// where every prepared is encoded as prefix | pattern | suffix | source | flags followed by its segments if it has any,
+// counts and string lengths are encoded as uvarints, the checksum covers everything before it
+func (m matcher) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBufferString(binaryMagic)
+	buf.WriteByte(binaryVersion)
+	writeUvarint(buf, uint64(len(m.prepared)))
+	for _, p := range m.prepared {
+		writePrepared(buf, p)
+		writeUvarint(buf, uint64(len(p.excludes)))
+		for _, e := range p.excludes {
+			writePrepared(buf, e)
+		}
+	}
+	checksum := crc32.ChecksumIEEE(buf.Bytes())
+	_ = binary.Write(buf, binary.BigEndian, checksum)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// predicates get resolved from the default registry
+func (m *matcher) UnmarshalBinary(data []byte) error {
+	return m.unmarshal(data, defaultRegistry)
+}
+
+func (m *matcher) unmarshal(data []byte, registry *Registry) error {
+	if len(data) < len(binaryMagic)+1+crc32.Size || string(data[:len(binaryMagic)]) != binaryMagic {
+		return ErrInvalidFormat
+	}
+	if data[len(binaryMagic)] != binaryVersion {
+		return ErrVersionMismatch
+	}
+	content, checksum := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	if crc32.ChecksumIEEE(content) != binary.BigEndian.Uint32(checksum) {
+		return ErrChecksum
+	}
+	r := bytes.NewReader(content[len(binaryMagic)+1:])
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ErrInvalidFormat
+	}
+	// every prepared needs at least four bytes, this guards against huge allocations
+	if count > uint64(r.Len()/4) {
+		return ErrInvalidFormat
+	}
+	ps := make([]prepared, count)
+	for i := range ps {
+		if ps[i], err = readPrepared(r, registry); err != nil {
+			return err
+		}
+		if ps[i].excludes, err = readExcludes(r, registry); err != nil {
+			return err
+		}
+	}
+	if r.Len() != 0 {
+		return ErrInvalidFormat
+	}
+	m.prepared = ps
+	return nil
+}
+
+func writePrepared(buf *bytes.Buffer, p prepared) {
+	writeString(buf, p.prefix)
+	writeString(buf, p.pattern)
+	writeString(buf, p.suffix)
+	writeString(buf, p.source)
+	flags := byte(0)
+	if p.advancedPattern {
+		flags |= flagAdvancedPattern
+	}
+	if p.segments != nil {
+		flags |= flagSegments
+	}
+	if p.optionalQuestion {
+		flags |= flagOptionalQuestion
+	}
+	buf.WriteByte(flags)
+	if p.segments != nil {
+		writeUvarint(buf, uint64(len(p.segments)))
+		for _, s := range p.segments {
+			writeSegment(buf, s)
+		}
+	}
+}
+
+func writeSegment(buf *bytes.Buffer, s segment) {
+	switch s := s.(type) {
+	case *repeatSegment:
+		buf.WriteByte(segmentKindRepeat)
+		writeUvarint(buf, uint64(s.min))
+		writeVarint(buf, int64(s.max))
+		writeBool(buf, s.optionalQuestion)
+		writeUvarint(buf, uint64(len(s.alternatives)))
+		for _, alternative := range s.alternatives {
+			writeString(buf, alternative)
+		}
+	case *regexpSegment:
+		buf.WriteByte(segmentKindRegexp)
+		writeString(buf, s.source)
+	case *rangeSegment:
+		buf.WriteByte(segmentKindRange)
+		writeVarint(buf, s.min)
+		writeVarint(buf, s.max)
+		writeUvarint(buf, uint64(s.width))
+	case *placeholderSegment:
+		buf.WriteByte(segmentKindPlaceholder)
+		writeString(buf, s.name)
+	case *predicateSegment:
+		buf.WriteByte(segmentKindPredicate)
+		writeString(buf, s.name)
+	case *captureSegment:
+		buf.WriteByte(segmentKindCapture)
+		writeUvarint(buf, uint64(s.index))
+		writeString(buf, s.name)
+		writeBool(buf, s.end)
+		writeString(buf, s.typ)
+	}
+}
+
+func readSegment(r *bytes.Reader, registry *Registry) (segment, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	switch kind {
+	case segmentKindRepeat:
+		min, err := binary.ReadUvarint(r)
+		if err != nil || min > maxRepetition {
+			return nil, ErrInvalidFormat
+		}
+		max, err := binary.ReadVarint(r)
+		if err != nil || max > maxRepetition || (max != -1 && max < int64(min)) {
+			return nil, ErrInvalidFormat
+		}
+		optionalQuestion, err := readBool(r)
+		if err != nil {
+			return nil, err
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil || count > uint64(r.Len()) {
+			return nil, ErrInvalidFormat
+		}
+		alternatives := make([]string, count)
+		for i := range alternatives {
+			if alternatives[i], err = readString(r); err != nil {
+				return nil, err
+			}
+		}
+		return newRepeatSegment(alternatives, int(min), int(max), optionalQuestion), nil
+	case segmentKindRegexp:
+		source, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		seg, err := newRegexpSegment(source)
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+		return seg, nil
+	case segmentKindRange:
+		min, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+		max, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+		width, err := binary.ReadUvarint(r)
+		if err != nil || width > 20 {
+			return nil, ErrInvalidFormat
+		}
+		return &rangeSegment{min: min, max: max, width: int(width)}, nil
+	case segmentKindPlaceholder:
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		seg, ok := newPlaceholderSegment(name)
+		if !ok {
+			return nil, ErrInvalidFormat
+		}
+		return seg, nil
+	case segmentKindPredicate:
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		seg, err := registry.newPredicateSegment(name)
+		if err != nil {
+			return nil, errors.New("unknown predicate @" + name)
+		}
+		return seg, nil
+	case segmentKindCapture:
+		index, err := binary.ReadUvarint(r)
+		if err != nil || index > uint64(r.Len()) {
+			return nil, ErrInvalidFormat
+		}
+		name, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		end, err := readBool(r)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		return &captureSegment{index: int(index), name: name, end: end, typ: typ}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+func readExcludes(r *bytes.Reader, registry *Registry) ([]prepared, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(r.Len()/4) {
+		return nil, ErrInvalidFormat
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	excludes := make([]prepared, count)
+	for i := range excludes {
+		if excludes[i], err = readPrepared(r, registry); err != nil {
+			return nil, err
+		}
+	}
+	return excludes, nil
+}
+
+func readPrepared(r *bytes.Reader, registry *Registry) (prepared, error) {
+	prefix, err := readString(r)
+	if err != nil {
+		return prepared{}, err
+	}
+	pattern, err := readString(r)
+	if err != nil {
+		return prepared{}, err
+	}
+	suffix, err := readString(r)
+	if err != nil {
+		return prepared{}, err
+	}
+	source, err := readString(r)
+	if err != nil {
+		return prepared{}, err
+	}
+	flags, err := r.ReadByte()
+	if err != nil {
+		return prepared{}, ErrInvalidFormat
+	}
+	var segments []segment
+	if flags&flagSegments != 0 {
+		count, err := binary.ReadUvarint(r)
+		if err != nil || count > uint64(r.Len()) {
+			return prepared{}, ErrInvalidFormat
+		}
+		segments = make([]segment, count)
+		for i := range segments {
+			if segments[i], err = readSegment(r, registry); err != nil {
+				return prepared{}, err
+			}
+		}
+	}
+	for _, seg := range segments {
+		if boundary, ok := seg.(*captureSegment); ok && boundary.index >= len(segments)/2 {
+			return prepared{}, ErrInvalidFormat
+		}
+	}
+	// every capture has to be opened once and closed once after it in the pattern
+	opened := map[int]bool{}
+	closed := map[int]bool{}
+	for rest := pattern; segments != nil; {
+		i := indexSegmentMarker(rest)
+		if i == -1 {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(rest[i:])
+		if segmentIndex(r) >= len(segments) {
+			return prepared{}, ErrInvalidFormat
+		}
+		if boundary, ok := segments[segmentIndex(r)].(*captureSegment); ok {
+			if closed[boundary.index] || opened[boundary.index] != boundary.end {
+				return prepared{}, ErrInvalidFormat
+			}
+			opened[boundary.index] = true
+			closed[boundary.index] = boundary.end
+		}
+		rest = rest[i+size:]
+	}
+	for index := range opened {
+		if !closed[index] {
+			return prepared{}, ErrInvalidFormat
+		}
+	}
+	return prepared{
+		prefix:           prefix,
+		prefixLen:        len(prefix),
+		pattern:          pattern,
+		suffix:           suffix,
+		suffixLen:        len(suffix),
+		advancedPattern:  flags&flagAdvancedPattern != 0,
+		optionalQuestion: flags&flagOptionalQuestion != 0,
+		segments:         segments,
+		source:           source,
+	}, nil
+}
+
+func writeUvarint(buf *bytes.Buffer, v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	buf.Write(scratch[:n])
+}
+
+func writeVarint(buf *bytes.Buffer, v int64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(scratch[:], v)
+	buf.Write(scratch[:n])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil || l > uint64(r.Len()) {
+		return "", ErrInvalidFormat
+	}
+	s := make([]byte, l)
+	_, _ = io.ReadFull(r, s)
+	return string(s), nil
+}
+
+func writeBool(buf *bytes.Buffer, b bool) {
+	if b {
+		buf.WriteByte(1)
+		return
+	}
+	buf.WriteByte(0)
+}
+
+func readBool(r *bytes.Reader) (bool, error) {
+	b, err := r.ReadByte()
+	if err != nil || b > 1 {
+		return false, ErrInvalidFormat
+	}
+	return b == 1, nil
+}
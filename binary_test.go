@@ -0,0 +1,175 @@
+package match_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	m, err := match.Compile("namespace.[ real | virtual ].[ root* ].value")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m, loaded)
+
+	assert.Equal(t, true, loaded.Matches("namespace.real.root.path.value"))
+	assert.Equal(t, true, loaded.Matches("namespace.virtual.root.value"))
+	assert.Equal(t, false, loaded.Matches("namespace.virtual.roo.value"))
+}
+
+func TestWriteRead(t *testing.T) {
+	m, err := match.Compile("test[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]")
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, match.Write(&buf, m))
+
+	loaded, err := match.Read(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, m, loaded)
+}
+
+func TestMarshalExclusions(t *testing.T) {
+	m, err := match.Compile("logs.[ * ! debug | trace ].out")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m, loaded)
+	assert.Equal(t, true, loaded.Matches("logs.info.out"))
+	assert.Equal(t, false, loaded.Matches("logs.debug.out"))
+}
+
+func TestMarshalRepetitions(t *testing.T) {
+	m, err := match.Compile("root.[ seg. ]+leaf")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m, loaded)
+	assert.Equal(t, true, loaded.Matches("root.seg.seg.leaf"))
+	assert.Equal(t, false, loaded.Matches("root.leaf"))
+}
+
+func TestMarshalRegexps(t *testing.T) {
+	m, err := match.Compile("users.[ /re:[0-9]+/ ].profile")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, true, loaded.Matches("users.123.profile"))
+	assert.Equal(t, false, loaded.Matches("users.abc.profile"))
+}
+
+func TestMarshalRanges(t *testing.T) {
+	m, err := match.Compile("shard-[ 000..127 | -5..-1 ]")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, m, loaded)
+	assert.Equal(t, true, loaded.Matches("shard-042"))
+	assert.Equal(t, true, loaded.Matches("shard--5"))
+	assert.Equal(t, false, loaded.Matches("shard-42"))
+}
+
+func TestMarshalCaptures(t *testing.T) {
+	m, err := match.Compile("users.[:id *].[:port :int]")
+	assert.NoError(t, err)
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+
+	groups, ok := match.Capture(loaded, "users.alice.8080")
+	assert.True(t, ok)
+	assert.Equal(t, match.Groups{
+		{Name: "id", Text: "alice", Start: 6, End: 11, Value: "alice"},
+		{Name: "port", Text: "8080", Start: 12, End: 16, Value: int64(8080)},
+	}, groups)
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	m, err := match.Compile("namespace.[ real | virtual ].[ root* ].value")
+	assert.NoError(t, err)
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	_, err = match.Unmarshal([]byte("something"))
+	assert.ErrorIs(t, err, match.ErrInvalidFormat)
+
+	_, err = match.Unmarshal(data[:len(data)-5])
+	assert.Error(t, err)
+
+	corrupted := append([]byte{}, data...)
+	corrupted[10] ^= 0xff
+	_, err = match.Unmarshal(corrupted)
+	assert.ErrorIs(t, err, match.ErrChecksum)
+
+	otherVersion := append([]byte{}, data...)
+	otherVersion[4]++
+	_, err = match.Unmarshal(otherVersion)
+	assert.ErrorIs(t, err, match.ErrVersionMismatch)
+}
+
+func TestUnmarshalUnpairedCaptures(t *testing.T) {
+	m, err := match.Compile("user=[:id *].")
+	assert.NoError(t, err)
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+	open, close := []byte("\x02id\x00\x00"), []byte("\x02id\x01\x00")
+	assert.Equal(t, 1, bytes.Count(data, open))
+	assert.Equal(t, 1, bytes.Count(data, close))
+
+	for name, mutate := range map[string]func([]byte) []byte{
+		"swapped": func(data []byte) []byte {
+			data = bytes.Replace(data, open, []byte("\x02id\x02\x00"), 1)
+			data = bytes.Replace(data, close, open, 1)
+			return bytes.Replace(data, []byte("\x02id\x02\x00"), close, 1)
+		},
+		"unclosed": func(data []byte) []byte {
+			return bytes.Replace(data, close, open, 1)
+		},
+		"unopened": func(data []byte) []byte {
+			return bytes.Replace(data, open, close, 1)
+		},
+	} {
+		mutated := mutate(append([]byte{}, data...))
+		content := mutated[:len(mutated)-crc32.Size]
+		binary.BigEndian.PutUint32(mutated[len(content):], crc32.ChecksumIEEE(content))
+		_, err := match.Unmarshal(mutated)
+		assert.ErrorIs(t, err, match.ErrInvalidFormat, name)
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	m, _ := match.Compile("namespace.[ a | b | c | d ].[ e | f | g | h ].[ root* | leaf? ].value")
+	data, _ := match.Marshal(m)
+
+	for n := 0; n < b.N; n++ {
+		_, _ = match.Unmarshal(data)
+	}
+}
@@ -0,0 +1,75 @@
+package match
+
+import "unsafe"
+
+// MatchesBytes reports if m matches the data. Compiled matchers without predicates and the combinations
+// of those view the data as a string instead of copying it, so it must not be modified concurrently.
+// Other matchers get a copy, since they could keep the string they get passed
+func MatchesBytes[B ~[]byte](m Matcher, data B) bool {
+	return matchesBytes(m, data, canView(m))
+}
+
+// MatchesString reports if m matches data of a named string type
+func MatchesString[S ~string](m Matcher, data S) bool {
+	return m.Matches(string(data))
+}
+
+// matchesBytes matches the data as a string view if view is set and as a copy otherwise
+func matchesBytes(m Matcher, data []byte, view bool) bool {
+	if !view {
+		return m.Matches(string(data))
+	}
+	s := unsafe.String(unsafe.SliceData(data), len(data))
+	if m, ok := m.(matcher); ok {
+		return matchMulti(m.prepared, s)
+	}
+	return m.Matches(s)
+}
+
+// canView reports if m only consists of compiled matchers without predicates and combinators,
+// none of them keeps the data it gets passed
+func canView(m Matcher) bool {
+	switch m := m.(type) {
+	case matcher:
+		return !hasPredicates(m.prepared)
+	case orMatcher:
+		return canViewAll(m)
+	case andMatcher:
+		return canViewAll(m)
+	case xorMatcher:
+		return canViewAll(m)
+	case firstOfMatcher:
+		return canViewAll(m)
+	case notMatcher:
+		return canView(m.m)
+	case prefixGuard:
+		return canView(m.m)
+	case shortestMatcher:
+		return canView(m.m)
+	}
+	return false
+}
+
+func canViewAll(ms []Matcher) bool {
+	for _, m := range ms {
+		if !canView(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPredicates reports if any of the prepared or their excludes call a predicate
+func hasPredicates(ps []prepared) bool {
+	for _, p := range ps {
+		for _, seg := range p.segments {
+			if _, ok := seg.(*predicateSegment); ok {
+				return true
+			}
+		}
+		if hasPredicates(p.excludes) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+type topic string
+
+type buffer []byte
+
+func TestMatchesBytes(t *testing.T) {
+	m, err := match.Compile("svc.[ api | web ].[ /re:v[0-9]+/ ].*")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, match.MatchesBytes(m, []byte("svc.api.v2.requests")))
+	assert.Equal(t, false, match.MatchesBytes(m, []byte("svc.db.v2.requests")))
+	assert.Equal(t, true, match.MatchesBytes(m, buffer("svc.web.v10.")))
+	assert.Equal(t, false, match.MatchesBytes(m, buffer(nil)))
+	assert.Equal(t, true, match.MatchesString(m, topic("svc.web.v1.x")))
+
+	data := []byte("svc.api.v2.requests.total")
+	allocs := testing.AllocsPerRun(100, func() {
+		match.MatchesBytes(m, data)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+type keeper struct {
+	kept []string
+}
+
+func (k *keeper) Matches(data string) bool {
+	k.kept = append(k.kept, data)
+	return true
+}
+
+func TestMatchesBytesCopiesForUnknownMatchers(t *testing.T) {
+	data := []byte("svc.api")
+
+	k := &keeper{}
+	assert.Equal(t, true, match.MatchesBytes(k, data))
+
+	registry := match.NewRegistry()
+	registry.RegisterPredicate("svc", func(data string) bool {
+		k.kept = append(k.kept, data)
+		return true
+	})
+	m, err := match.Compile("[@svc].api", match.WithRegistry(registry))
+	assert.NoError(t, err)
+	assert.Equal(t, true, match.MatchesBytes(m, data))
+
+	copy(data, "xxx.xxx")
+	assert.Equal(t, []string{"svc.api", "svc"}, k.kept)
+}
@@ -0,0 +1,64 @@
+package match
+
+import "strings"
+
+type canonicalizer interface {
+	canonical(data string) (string, bool)
+}
+
+// Canonical matches the data and returns the source text of the alternative that matched it,
+// data like users.8123.profile matched by users.[ * | admin ].profile becomes users.*.profile.
+// This reduces the cardinality of values like metric labels to the patterns they belong to.
+// If more than one alternative matches the one with the most static text wins.
+//
+// Matchers that do not know their source, like the ones returned by And or Not, return the data itself if they match
+func Canonical(m Matcher, data string) (string, bool) {
+	if c, ok := m.(canonicalizer); ok {
+		return c.canonical(data)
+	}
+	if m.Matches(data) {
+		return data, true
+	}
+	return "", false
+}
+
+// canonical returns the source of the most specific prepared that matches,
+// that is the one with the longest static prefix and suffix
+func (m matcher) canonical(data string) (string, bool) {
+	l := len(data)
+	best := -1
+	for i := len(m.prepared) - 1; i >= 0; i-- {
+		p := m.prepared[i]
+		if (best == -1 || p.prefixLen+p.suffixLen > m.prepared[best].prefixLen+m.prepared[best].suffixLen) && matchSingle(p, data, l) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return "", false
+	}
+	return m.prepared[best].source, true
+}
+
+func (o orMatcher) canonical(data string) (string, bool) {
+	for _, m := range o {
+		if source, ok := Canonical(m, data); ok {
+			return source, true
+		}
+	}
+	return "", false
+}
+
+func (f firstOfMatcher) canonical(data string) (string, bool) {
+	return orMatcher(f).canonical(data)
+}
+
+func (g prefixGuard) canonical(data string) (string, bool) {
+	if !strings.HasPrefix(data, g.prefix) {
+		return "", false
+	}
+	return Canonical(g.m, data)
+}
+
+func (s shortestMatcher) canonical(data string) (string, bool) {
+	return Canonical(s.m, data)
+}
@@ -0,0 +1,58 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonical(t *testing.T) {
+	m, err := match.Compile("users.[ * | admin ].[ profile | settings ] | [:id :int].[ a | b ]{1,2}")
+	assert.NoError(t, err)
+
+	for data, expected := range map[string]string{
+		"users.8123.profile":   "users.*.profile",
+		"users.42.settings":    "users.*.settings",
+		"users.admin.settings": "users.admin.settings",
+		"7.ab":                 "[:id :int].[ a | b ]{1,2}",
+	} {
+		canonical, ok := match.Canonical(m, data)
+		assert.True(t, ok, data)
+		assert.Equal(t, expected, canonical, data)
+	}
+	_, ok := match.Canonical(m, "groups.1.profile")
+	assert.False(t, ok)
+
+	// the source survives the binary encoding and the combinators
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+	decoded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	canonical, ok := match.Canonical(match.And(m, decoded), "users.1.profile")
+	assert.True(t, ok)
+	assert.Equal(t, "users.1.profile", canonical)
+	canonical, ok = match.Canonical(match.Or(decoded, m), "users.1.profile")
+	assert.True(t, ok)
+	assert.Equal(t, "users.*.profile", canonical)
+
+	m, err = match.Compile("id=[ :int ]", match.WithUnanchored())
+	assert.NoError(t, err)
+	canonical, ok = match.Canonical(m, "request id=12 failed")
+	assert.True(t, ok)
+	assert.Equal(t, "*id=[:int]*", canonical)
+
+	// the source is the text of the query, not the parsed alternatives
+	for _, test := range []struct{ pattern, data, expected string }{
+		{`"a b".[ * | x ]`, "a b.c", `"a b".*`},
+		{`a.[ * ! b ]`, "a.c", `a.[* ! b]`},
+		{`a\*b[ \| | q ]`, "a*b|", `a\*b\|`},
+		{`[:id a | b ! c]-x`, "a-x", `[:id a ! c]-x`},
+	} {
+		m, err := match.Compile(test.pattern)
+		assert.NoError(t, err, test.pattern)
+		canonical, ok := match.Canonical(m, test.data)
+		assert.True(t, ok, test.pattern)
+		assert.Equal(t, test.expected, canonical, test.pattern)
+	}
+}
@@ -0,0 +1,130 @@
+package match
+
+import "strings"
+
+// Group is a named capture of a pattern like [:id *] and the part of the data it matched
+type Group struct {
+	Name string
+	Text string
+	// Start and End are the byte offsets of the text inside the data
+	Start int
+	End   int
+	// Value is the parsed value if the group only contains a typed placeholder like [:port :int],
+	// otherwise it is the text
+	Value any
+}
+
+// Groups are the captured groups of a match in the order they appear in the pattern
+type Groups []Group
+
+type capturer interface {
+	capture(data string) (Groups, bool)
+}
+
+// captureSegment marks the start or the end of a named capture, it does not consume any data
+type captureSegment struct {
+	index int
+	name  string
+	end   bool
+	// typ is the name of the placeholder type whose value gets captured, it is empty if the text gets captured
+	typ string
+}
+
+// Capture matches the data and returns the named groups of the matching pattern.
+// Matchers that do not support captures only report if they match
+func Capture(m Matcher, data string) (Groups, bool) {
+	if c, ok := m.(capturer); ok {
+		return c.capture(data)
+	}
+	return nil, m.Matches(data)
+}
+
+// Get returns the group with the given name
+func (g Groups) Get(name string) (Group, bool) {
+	for _, group := range g {
+		if group.Name == name {
+			return group, true
+		}
+	}
+	return Group{}, false
+}
+
+func (s *captureSegment) matches(data string) bool {
+	return data == ""
+}
+
+// slot returns the index of the boundary inside the recorded captures
+func (s *captureSegment) slot() int {
+	if s.end {
+		return s.index*2 + 1
+	}
+	return s.index * 2
+}
+
+func (m matcher) capture(data string) (Groups, bool) {
+	l := len(data)
+	for i := len(m.prepared) - 1; i >= 0; i-- {
+		if matchSingle(m.prepared[i], data, l) {
+			return capturePrepared(m.prepared[i], data), true
+		}
+	}
+	return nil, false
+}
+
+// capturePrepared extracts the groups of a prepared that is known to match the data
+func capturePrepared(p prepared, data string) Groups {
+	boundaries := []*captureSegment{}
+	for _, seg := range p.segments {
+		if boundary, ok := seg.(*captureSegment); ok && !boundary.end {
+			boundaries = append(boundaries, boundary)
+		}
+	}
+	if len(boundaries) == 0 {
+		return Groups{}
+	}
+	middle := data[p.prefixLen : len(data)-p.suffixLen]
+	w := wildcardMatch{
+		optionalQuestion: p.optionalQuestion,
+		segments:         p.segments,
+		captures:         make([]int, len(p.segments)*2),
+		length:           len(middle),
+	}
+	w.deepMatch(middle, p.pattern)
+	groups := make(Groups, 0, len(boundaries))
+	for _, boundary := range boundaries {
+		start := p.prefixLen + w.captures[boundary.index*2]
+		end := p.prefixLen + w.captures[boundary.index*2+1]
+		group := Group{
+			Name:  boundary.name,
+			Text:  data[start:end],
+			Start: start,
+			End:   end,
+			Value: data[start:end],
+		}
+		if parse, ok := placeholderTypes[boundary.typ]; ok {
+			group.Value, _ = parse(group.Text)
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func (o orMatcher) capture(data string) (Groups, bool) {
+	for _, m := range o {
+		if groups, ok := Capture(m, data); ok {
+			return groups, true
+		}
+	}
+	return nil, false
+}
+
+func (f firstOfMatcher) capture(data string) (Groups, bool) {
+	return orMatcher(f).capture(data)
+}
+
+func (g prefixGuard) capture(data string) (Groups, bool) {
+	if !strings.HasPrefix(data, g.prefix) {
+		return nil, false
+	}
+	return Capture(g.m, data)
+}
@@ -0,0 +1,84 @@
+package match_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedPlaceholders(t *testing.T) {
+	m, err := match.Compile("users.[:int].[:word]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("users.42.profile"))
+	assert.Equal(t, false, m.Matches("users.me.profile"))
+	assert.Equal(t, false, m.Matches("users.42.pro-file"))
+
+	m, err = match.Compile("hosts.[:ipv4 | localhost].[:uuid]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("hosts.10.0.0.1.123e4567-e89b-12d3-a456-426614174000"))
+	assert.Equal(t, true, m.Matches("hosts.localhost.123e4567-e89b-12d3-a456-426614174000"))
+	assert.Equal(t, false, m.Matches("hosts.10.0.0.1.123e4567"))
+
+	_, err = match.Compile("users.[:float]")
+	assert.Error(t, err)
+}
+
+func TestCapture(t *testing.T) {
+	m, err := match.Compile("users.[:id *].[:port :int].[ a | b ]")
+	assert.NoError(t, err)
+
+	groups, ok := match.Capture(m, "users.alice.8080.a")
+	assert.True(t, ok)
+	assert.Equal(t, match.Groups{
+		{Name: "id", Text: "alice", Start: 6, End: 11, Value: "alice"},
+		{Name: "port", Text: "8080", Start: 12, End: 16, Value: int64(8080)},
+	}, groups)
+
+	port, ok := groups.Get("port")
+	assert.True(t, ok)
+	assert.Equal(t, int64(8080), port.Value)
+
+	_, ok = groups.Get("missing")
+	assert.False(t, ok)
+
+	_, ok = match.Capture(m, "users.alice.http.a")
+	assert.False(t, ok)
+
+	m, err = match.Compile("[:name grü* | x ]ß.[:rest * ]")
+	assert.NoError(t, err)
+
+	groups, ok = match.Capture(m, "grüneß.end")
+	assert.True(t, ok)
+	assert.Equal(t, "grüne", groups[0].Text)
+	assert.Equal(t, "grüneß.end"[groups[1].Start:groups[1].End], groups[1].Text)
+	assert.Equal(t, "end", groups[1].Text)
+
+	groups, ok = match.Capture(match.Or(match.Not(m), m), "x")
+	assert.True(t, ok)
+	assert.Nil(t, groups)
+
+	_, err = match.Compile("[:id * ].[:id * ]")
+	assert.Error(t, err)
+
+	_, err = match.Compile("[:1d * ]")
+	assert.Error(t, err)
+}
+
+func TestCaptureBoundariesAreZeroWidth(t *testing.T) {
+	// capture boundaries must not make plain matching try the rest of the pattern at every offset
+	m, err := match.Compile("[:a *]-[:b *]-[:c *]-[:d *]")
+	assert.NoError(t, err)
+	key, err := match.Compile("users.[:id *].profile")
+	assert.NoError(t, err)
+
+	start := time.Now()
+	assert.Equal(t, false, m.Matches(strings.Repeat("x", 80)+"-"+strings.Repeat("y", 80)))
+	assert.Equal(t, false, key.Matches("users."+strings.Repeat("x", 20000)))
+	assert.Equal(t, true, key.Matches("users."+strings.Repeat("x", 20000)+".profile"))
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+}
@@ -15,7 +15,7 @@ func iterCartesian(params ...[]string) chan []string {
 	c := make(chan []string)
 	var wg sync.WaitGroup
 	wg.Add(1)
-	iterateCartesian(&wg, c, []string{}, params...)
+	go iterateCartesian(&wg, c, []string{}, params...)
 	go func() { wg.Wait(); close(c) }()
 	return c
 }
@@ -46,3 +46,7 @@ func TestCartesian(t *testing.T) {
 	assert.ElementsMatch(t, expected, actual)
 
 }
+
+func TestCartesianWithoutParams(t *testing.T) {
+	assert.Equal(t, [][]string{{}}, cartesian())
+}
@@ -0,0 +1,88 @@
+package match
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// Unmatched is the name of the bucket of the inputs no pattern of a classifier matches
+const Unmatched = "unmatched"
+
+// Bucket is a named pattern of a classifier
+type Bucket struct {
+	Name    string
+	Pattern string
+}
+
+// Classifier sends every input to the first bucket whose pattern matches it
+type Classifier struct {
+	names []string
+	// prepared contains the prepared matchers of all buckets in the order of the buckets,
+	// inside of a bucket the cheapest prepared comes first
+	prepared []bucketPrepared
+}
+
+type bucketPrepared struct {
+	bucket int
+	p      prepared
+}
+
+// CompileClassifier compiles the patterns of the buckets into a single classifier,
+// the order of the buckets decides which one wins if more than one matches
+func CompileClassifier(buckets []Bucket, opts ...Option) (*Classifier, error) {
+	c := &Classifier{}
+	for i, bucket := range buckets {
+		if bucket.Name == Unmatched {
+			return nil, errors.New("bucket name " + Unmatched + " is reserved")
+		}
+		m, err := Compile(bucket.Pattern, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %s: %w", bucket.Name, err)
+		}
+		ps := m.(matcher).prepared
+		for j := len(ps) - 1; j >= 0; j-- {
+			c.prepared = append(c.prepared, bucketPrepared{bucket: i, p: ps[j]})
+		}
+		c.names = append(c.names, bucket.Name)
+	}
+	return c, nil
+}
+
+// Names returns the names of the buckets in their order followed by Unmatched
+func (c *Classifier) Names() []string {
+	return append(append([]string{}, c.names...), Unmatched)
+}
+
+// Classify returns the name of the first bucket that matches the data, or Unmatched
+func (c *Classifier) Classify(data string) string {
+	l := len(data)
+	for i := range c.prepared {
+		if matchSingle(c.prepared[i].p, data, l) {
+			return c.names[c.prepared[i].bucket]
+		}
+	}
+	return Unmatched
+}
+
+// GroupBy classifies all inputs and returns them grouped by the names of their buckets,
+// only buckets with at least one input are part of the result
+func (c *Classifier) GroupBy(inputs []string) map[string][]string {
+	groups := map[string][]string{}
+	for _, input := range inputs {
+		bucket := c.Classify(input)
+		groups[bucket] = append(groups[bucket], input)
+	}
+	return groups
+}
+
+// GroupBySeq classifies the inputs while they are consumed and yields the name of the bucket together with every input
+func (c *Classifier) GroupBySeq(inputs iter.Seq[string]) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		for input := range inputs {
+			if !yield(c.Classify(input), input) {
+				return
+			}
+		}
+	}
+}
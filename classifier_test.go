@@ -0,0 +1,49 @@
+package match_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifier(t *testing.T) {
+	c, err := match.CompileClassifier([]match.Bucket{
+		{Name: "compute", Pattern: "[ vm | container ].*"},
+		{Name: "storage", Pattern: "[ bucket | disk ].* | *.backup"},
+		{Name: "backups", Pattern: "vm.*.backup"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"compute", "storage", "backups", match.Unmatched}, c.Names())
+
+	assert.Equal(t, "compute", c.Classify("vm.web-1"))
+	// the first matching bucket wins even though the later one is more specific
+	assert.Equal(t, "compute", c.Classify("vm.web-1.backup"))
+	assert.Equal(t, "storage", c.Classify("db.backup"))
+	assert.Equal(t, match.Unmatched, c.Classify("network.lb"))
+
+	inputs := []string{"vm.a", "disk.b", "network.c", "container.d", "db.backup"}
+	assert.Equal(t, map[string][]string{
+		"compute":       {"vm.a", "container.d"},
+		"storage":       {"disk.b", "db.backup"},
+		match.Unmatched: {"network.c"},
+	}, c.GroupBy(inputs))
+
+	buckets := []string{}
+	for bucket, input := range c.GroupBySeq(slices.Values(inputs)) {
+		buckets = append(buckets, bucket+"="+input)
+		if len(buckets) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"compute=vm.a", "storage=disk.b", "unmatched=network.c"}, buckets)
+}
+
+func TestClassifierErrors(t *testing.T) {
+	_, err := match.CompileClassifier([]match.Bucket{{Name: "a", Pattern: "x.[ :nope ]"}})
+	assert.ErrorContains(t, err, "bucket a")
+
+	_, err = match.CompileClassifier([]match.Bucket{{Name: match.Unmatched, Pattern: "*"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package match
+
+type orMatcher []Matcher
+
+type andMatcher []Matcher
+
+type notMatcher struct {
+	m Matcher
+}
+
+type xorMatcher []Matcher
+
+type firstOfMatcher []Matcher
+
+// Or returns a matcher that matches if any of the given matchers matches.
+//
+// If all matchers are returned by Compile their prepared matchers get merged
+// and ordered by complexity, the result behaves like a single compiled pattern
+func Or(ms ...Matcher) Matcher {
+	if merged, ok := mergeMatchers(ms); ok {
+		return merged
+	}
+	return orMatcher(ms)
+}
+
+// And returns a matcher that matches if all of the given matchers match
+func And(ms ...Matcher) Matcher {
+	return andMatcher(ms)
+}
+
+// Not returns a matcher that matches if the given matcher does not match
+func Not(m Matcher) Matcher {
+	if n, ok := m.(notMatcher); ok {
+		return n.m
+	}
+	return notMatcher{m: m}
+}
+
+// Xor returns a matcher that matches if an odd number of the given matchers match
+func Xor(ms ...Matcher) Matcher {
+	return xorMatcher(ms)
+}
+
+// FirstOf returns a matcher that checks the given matchers in exactly the given order
+// and stops at the first one that matches.
+// Unlike Or it never merges or reorders its matchers, which is useful if the caller
+// knows which matcher hits most often
+func FirstOf(ms ...Matcher) Matcher {
+	return firstOfMatcher(ms)
+}
+
+func mergeMatchers(ms []Matcher) (matcher, bool) {
+	if len(ms) == 0 {
+		return matcher{}, false
+	}
+	merged := []prepared{}
+	for _, m := range ms {
+		cm, ok := m.(matcher)
+		if !ok {
+			return matcher{}, false
+		}
+		merged = append(merged, cm.prepared...)
+	}
+	return matcher{
+		prepared: orderPreparedByComplexity(merged),
+	}, true
+}
+
+func (o orMatcher) Matches(data string) bool {
+	for _, m := range o {
+		if m.Matches(data) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a andMatcher) Matches(data string) bool {
+	for _, m := range a {
+		if !m.Matches(data) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n notMatcher) Matches(data string) bool {
+	return !n.m.Matches(data)
+}
+
+func (x xorMatcher) Matches(data string) bool {
+	matched := false
+	for _, m := range x {
+		if m.Matches(data) {
+			matched = !matched
+		}
+	}
+	return matched
+}
+
+func (f firstOfMatcher) Matches(data string) bool {
+	for _, m := range f {
+		if m.Matches(data) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type funcMatcher func(data string) bool
+
+func (f funcMatcher) Matches(data string) bool {
+	return f(data)
+}
+
+func mustCompile(t *testing.T, pattern string) Matcher {
+	m, err := Compile(pattern)
+	assert.NoError(t, err)
+	return m
+}
+
+func TestOr(t *testing.T) {
+	m := Or(
+		mustCompile(t, "namespace.[ real | virtual ].value"),
+		mustCompile(t, "other.[ * ]"),
+	)
+	// compiled matchers get merged into a single ordered plan
+	merged, ok := m.(matcher)
+	assert.True(t, ok)
+	assert.Len(t, merged.prepared, 3)
+	assert.Equal(t, orderPreparedByComplexity(append([]prepared{}, merged.prepared...)), merged.prepared)
+
+	assert.Equal(t, true, m.Matches("namespace.real.value"))
+	assert.Equal(t, true, m.Matches("other.thing"))
+	assert.Equal(t, false, m.Matches("namespace.fake.value"))
+
+	m = Or(mustCompile(t, "a[ * ]"), funcMatcher(func(data string) bool { return data == "b" }))
+	_, ok = m.(matcher)
+	assert.False(t, ok)
+	assert.Equal(t, true, m.Matches("abc"))
+	assert.Equal(t, true, m.Matches("b"))
+	assert.Equal(t, false, m.Matches("bc"))
+}
+
+func TestAnd(t *testing.T) {
+	m := And(mustCompile(t, "a[ * ]"), mustCompile(t, "[ * ]z"))
+	assert.Equal(t, true, m.Matches("abcz"))
+	assert.Equal(t, false, m.Matches("abc"))
+	assert.Equal(t, false, m.Matches("bcz"))
+}
+
+func TestNot(t *testing.T) {
+	inner := mustCompile(t, "a[ * ]")
+	m := Not(inner)
+	assert.Equal(t, false, m.Matches("abc"))
+	assert.Equal(t, true, m.Matches("bc"))
+	assert.Equal(t, inner, Not(m))
+}
+
+func TestXor(t *testing.T) {
+	m := Xor(mustCompile(t, "a[ * ]"), mustCompile(t, "[ * ]z"))
+	assert.Equal(t, false, m.Matches("abcz"))
+	assert.Equal(t, true, m.Matches("abc"))
+	assert.Equal(t, true, m.Matches("bcz"))
+	assert.Equal(t, false, m.Matches("bc"))
+}
+
+func TestFirstOf(t *testing.T) {
+	calls := []int{}
+	counting := func(i int, result bool) Matcher {
+		return funcMatcher(func(data string) bool {
+			calls = append(calls, i)
+			return result
+		})
+	}
+	m := FirstOf(counting(0, false), counting(1, true), counting(2, true))
+	assert.Equal(t, true, m.Matches("data"))
+	assert.Equal(t, []int{0, 1}, calls)
+}
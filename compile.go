@@ -3,13 +3,85 @@ package match
 import (
 	"errors"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 type part struct {
-	static   bool
-	content  string
-	patterns []string
+	static     bool
+	content    string
+	patterns   []string
+	exclusions []string
+
+	// repeat is set if the group is followed by a quantifier
+	repeat *repetition
+	// segments contains the segments of the group, the patterns reference them by their markers
+	segments []segment
+	// sources contains the source text of every pattern, it is nil if every pattern is its own source
+	sources []string
+
+	// offset is the position of the content in the query, offsets contains the position of every byte
+	// of the content and of its end. It is nil if the content was copied from the query as it is,
+	// otherwise raw holds the text of the query the content was parsed from
+	offset  int
+	offsets []int
+	raw     string
+}
+
+// take appends the text of the query between from and to to the content
+func (p *part) take(query string, from int, to int) {
+	p.content += query[from:to]
+	for i := from; i < to; i++ {
+		p.offsets = append(p.offsets, i)
+	}
+}
+
+// add appends text that replaces the text of the query at pos to the content
+func (p *part) add(text string, pos int) {
+	p.content += text
+	for range len(text) {
+		p.offsets = append(p.offsets, pos)
+	}
+}
+
+// finish trims the content, end is the position in the query where the content ended
+func (p *part) finish(query string, end int, cfg config) {
+	p.offsets = append(p.offsets, end)
+	trimmed := cfg.trim(p.content)
+	start := strings.Index(p.content, trimmed)
+	p.content, p.offsets = trimmed, p.offsets[start:start+len(trimmed)+1]
+	p.offset = p.offsets[0]
+	for i, pos := range p.offsets {
+		if pos != p.offset+i {
+			p.raw = query[p.offset:p.offsets[len(trimmed)]]
+			return
+		}
+	}
+	p.offsets = nil
+}
+
+// position returns the position in the query of the byte of the content at i
+func (p part) position(i int) int {
+	if p.offsets == nil {
+		return p.offset + i
+	}
+	return p.offsets[i]
+}
+
+// source returns the text of the query the content between from and to was parsed from
+func (p part) source(from int, to int) string {
+	if p.offsets == nil {
+		return p.content[from:to]
+	}
+	return p.raw[p.offsets[from]-p.offset : p.offsets[to]-p.offset]
+}
+
+// repetition describes how often a group has to repeat, a max of -1 means unbounded
+type repetition struct {
+	min int
+	max int
 }
 
 type prepared struct {
@@ -20,64 +92,563 @@ type prepared struct {
 	prefixLen int
 	suffixLen int
 
-	advancedPattern bool
+	advancedPattern  bool
+	optionalQuestion bool
+
+	// excludes contain the prepared matchers of the negative alternatives,
+	// data matched by one of them gets subtracted from the matches of this prepared
+	excludes []prepared
+
+	// segments contains the segments referenced by the markers in the pattern
+	segments []segment
+
+	// source is the text of the alternative of the pattern this prepared was generated from
+	source string
 }
 
-func parseQueryIntoParts(query string) ([]part, error) {
+func parseQueryIntoParts(query string, cfg config) ([]part, error) {
 	parts := []part{}
+	if query == "" {
+		return parts, nil
+	}
 	cp := part{static: query[0] != '['}
+	skip := 0
 	for i, r := range query {
+		if i < skip {
+			continue
+		}
+		// regular expressions are taken as they are, they can contain brackets
+		if !cp.static && strings.HasPrefix(query[i:], regexpOpen) {
+			end := regexpEnd(query, i)
+			if end == -1 {
+				return parts, errors.New("invalid query: expected '/' after regular expression")
+			}
+			cp.take(query, i, end)
+			skip = end
+			continue
+		}
+		// an escaped rune is taken as it is together with its backslash,
+		// the backslash gets removed when the pattern is prepared
+		if r == '\\' {
+			_, size := utf8.DecodeRuneInString(query[i+1:])
+			if size == 0 {
+				return parts, errors.New("invalid query: expected character after '\\'")
+			}
+			cp.take(query, i, i+1+size)
+			skip = i + 1 + size
+			continue
+		}
+		// quoted literals get escaped rune by rune, so their whitespace survives the trimming
+		if r == '"' {
+			end := quoteEnd(query, i)
+			if end == -1 {
+				return parts, errors.New("invalid query: expected '\"' after quoted literal")
+			}
+			cp.add(QuoteMeta(unescape(query[i+1:end-1])), i)
+			skip = end
+			continue
+		}
+		// runes of the marker range get escaped, so they never get mistaken for a segment
+		if isSegmentMarker(r) {
+			cp.add("\\"+string(r), i)
+			continue
+		}
 		switch r {
 		case '[':
 			if i == 0 {
 				continue
 			}
-			cp.content = strings.TrimSpace(cp.content)
+			cp.finish(query, i, cfg)
 			parts = append(parts, cp)
 			cp = part{static: false}
 		case ']':
 			if cp.static {
 				return parts, errors.New("invalid query: expected '[' before ']'")
 			}
-			cp.content = strings.TrimSpace(cp.content)
+			repeat, n, err := parseRepetition(query[i+1:])
+			if err != nil {
+				return parts, err
+			}
+			cp.finish(query, i, cfg)
+			cp.repeat = repeat
 			parts = append(parts, cp)
-			if len(query) > i+1 {
-				cp = part{static: query[i+1] != '['}
+			skip = i + 1 + n
+			if len(query) > skip {
+				cp = part{static: query[skip] != '['}
 			} else {
 				cp = part{}
 			}
 		default:
-			cp.content += string(r)
+			if _, size := utf8.DecodeRuneInString(query[i:]); r != utf8.RuneError || size > 1 {
+				cp.take(query, i, i+size)
+			} else {
+				cp.add(string(r), i)
+			}
 		}
 	}
 	if len(cp.content) > 0 {
-		cp.content = strings.TrimSpace(cp.content)
+		cp.finish(query, len(query), cfg)
 		parts = append(parts, cp)
 	}
 	return parts, nil
 }
 
-func parsePatterns(parts []part) []part {
+// splitOutsideGroups splits the pattern at every separator outside of brackets, quoted literals and escapes
+func splitOutsideGroups(pattern string, sep string) []string {
+	parts := []string{}
+	group := false
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == '"':
+			if end := quoteEnd(pattern, i); end != -1 {
+				i = end - 1
+			}
+		case group && strings.HasPrefix(pattern[i:], regexpOpen):
+			if end := regexpEnd(pattern, i); end != -1 {
+				i = end - 1
+			}
+		case c == '[':
+			group = true
+		case c == ']':
+			group = false
+		case !group && strings.HasPrefix(pattern[i:], sep):
+			parts = append(parts, pattern[start:i])
+			start = i + len(sep)
+			i = start - 1
+		}
+	}
+	return append(parts, pattern[start:])
+}
+
+// extendedFlag at the start of a pattern turns on the extended syntax
+const extendedFlag = "(?x)"
+
+// stripExtended removes the whitespace and the comments from a pattern written in the extended syntax,
+// a comment starts with # and ends with the line. Escaped runes, quoted literals and regular expressions are kept as they are.
+// The offsets contain the index in the pattern of every byte of the stripped pattern
+func stripExtended(pattern string) (string, []int) {
+	stripped := strings.Builder{}
+	offsets := []int{}
+	keep := func(from, to int) {
+		stripped.WriteString(pattern[from:to])
+		for j := from; j < to; j++ {
+			offsets = append(offsets, j)
+		}
+	}
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			keep(i, i+2)
+			i++
+			continue
+		case c == '"':
+			if end := quoteEnd(pattern, i); end != -1 {
+				keep(i, end)
+				i = end - 1
+				continue
+			}
+		case strings.HasPrefix(pattern[i:], regexpOpen):
+			if end := regexpEnd(pattern, i); end != -1 {
+				keep(i, end)
+				i = end - 1
+				continue
+			}
+		case c == '#':
+			end := strings.IndexByte(pattern[i:], '\n')
+			if end == -1 {
+				return stripped.String(), offsets
+			}
+			i += end
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			continue
+		}
+		keep(i, i+1)
+	}
+	return stripped.String(), offsets
+}
+
+// String formats the repetition as its shortest quantifier
+func (r repetition) String() string {
+	switch {
+	case r.min == 1 && r.max == -1:
+		return "+"
+	case r.min == 0 && r.max == -1:
+		return "*"
+	case r.min == 0 && r.max == 1:
+		return "?"
+	case r.max == -1:
+		return "{" + strconv.Itoa(r.min) + ",}"
+	case r.min == r.max:
+		return "{" + strconv.Itoa(r.min) + "}"
+	}
+	return "{" + strconv.Itoa(r.min) + "," + strconv.Itoa(r.max) + "}"
+}
+
+// maxRepetition is the highest bound a quantifier can have
+const maxRepetition = 1000
+
+// parseRepetition parses the quantifier at the start of the given rest of a query,
+// it returns the repetition and the amount of bytes the quantifier takes up
+//
+// # The following quantifiers are supported:
+//
+// + (one or more), * (zero or more), ? (zero or one), {n}, {min,}, {min,max} and {,max}
+func parseRepetition(rest string) (*repetition, int, error) {
+	if len(rest) == 0 {
+		return nil, 0, nil
+	}
+	switch rest[0] {
+	case '+':
+		return &repetition{min: 1, max: -1}, 1, nil
+	case '*':
+		return &repetition{min: 0, max: -1}, 1, nil
+	case '?':
+		return &repetition{min: 0, max: 1}, 1, nil
+	case '{':
+	default:
+		return nil, 0, nil
+	}
+	end := strings.IndexByte(rest, '}')
+	if end == -1 {
+		return nil, 0, errors.New("invalid query: expected '}' after '{'")
+	}
+	minStr, maxStr, bounded := strings.Cut(rest[1:end], ",")
+	minStr, maxStr = strings.TrimSpace(minStr), strings.TrimSpace(maxStr)
+	if !bounded {
+		maxStr = minStr
+	}
+	repeat := &repetition{min: 0, max: -1}
+	var err error
+	if minStr != "" {
+		if repeat.min, err = strconv.Atoi(minStr); err != nil || repeat.min < 0 || repeat.min > maxRepetition {
+			return nil, 0, errors.New("invalid query: invalid minimum in quantifier " + rest[:end+1])
+		}
+	}
+	if maxStr != "" {
+		if repeat.max, err = strconv.Atoi(maxStr); err != nil || repeat.max < repeat.min || repeat.max == 0 || repeat.max > maxRepetition {
+			return nil, 0, errors.New("invalid query: invalid maximum in quantifier " + rest[:end+1])
+		}
+	}
+	return repeat, end + 1, nil
+}
+
+// parsePatterns splits the content of every group into its alternatives.
+// Alternatives after a '!' are exclusions, they remove matches from the alternatives before it.
+// A group without positive alternatives like [ ! debug ] matches everything except its exclusions.
+//
+// Regular expressions like /re:[0-9]+/, ranges like 0..127, typed placeholders like :int
+// and predicates like @tenant get replaced by the marker of their segment. A group like [:id *] captures the data matched by its
+// alternatives under the given name, the capture boundaries are markers of zero width segments.
+//
+// Bounded repetitions get expanded into all sequences of the alternatives, so they can go
+// through the cartesian product. Unbounded repetitions and repetitions with more than
+// maxRepeatExpansion sequences become segments matched by an nfa
+func parsePatterns(parts []part, cfg config) ([]part, error) {
+	segments := 0
+	captures := map[string]bool{}
 	for i := range parts {
 		if parts[i].static {
 			continue
 		}
-		for _, pattern := range strings.Split(parts[i].content, "|") {
-			parts[i].patterns = append(parts[i].patterns, strings.TrimSpace(pattern))
+		name, content, err := cutCaptureName(parts[i].content)
+		if err != nil {
+			return parts, err
+		}
+		if captures[name] {
+			return parts, errors.New("invalid query: capture " + name + " is defined more than once")
+		}
+		// at is the position of the alternatives inside of the content of the part,
+		// the starts contain the position of every single alternative
+		at := 0
+		if name != "" {
+			at = len(parts[i].content) - len(strings.TrimLeftFunc(parts[i].content[len(name)+1:], unicode.IsSpace))
+		}
+		positive, negative, excluding := cutOutsideRegexp(content, '!')
+		implicit := excluding && strings.TrimSpace(positive) == ""
+		if implicit {
+			positive = "*"
+		}
+		var positiveStarts, negativeStarts []int
+		parts[i].patterns, positiveStarts = splitAlternativesAt(positive, at, cfg)
+		// the sources are the alternatives as they were written in the query
+		sources := make([]string, len(parts[i].patterns))
+		for j, start := range positiveStarts {
+			if !implicit {
+				sources[j] = parts[i].source(start, start+len(parts[i].patterns[j]))
+			}
+		}
+		exclusion := ""
+		if excluding {
+			negativeAt := at + len(content) - len(negative)
+			parts[i].exclusions, negativeStarts = splitAlternativesAt(negative, negativeAt, cfg)
+			exclusion = "! " + strings.TrimSpace(parts[i].source(negativeAt, at+len(content)))
+		}
+		bracketed := make([]bool, len(sources))
+		for k, alternatives := range [][]string{parts[i].patterns, parts[i].exclusions} {
+			starts := [][]int{positiveStarts, negativeStarts}[k]
+			for j := range alternatives {
+				pattern, replaced, err := replaceSegments(alternatives[j], segments, cfg)
+				if patternErr, ok := err.(*PatternError); ok {
+					patternErr.Pos = parts[i].position(starts[j] + patternErr.Pos)
+				}
+				if err != nil {
+					return parts, err
+				}
+				alternatives[j] = pattern
+				if k == 0 && replaced != nil {
+					bracketed[j] = true
+				}
+				parts[i].segments = append(parts[i].segments, replaced...)
+				segments += len(replaced)
+			}
+		}
+		// alternatives with segments, exclusions or a capture name only keep their meaning inside of brackets
+		for j := range sources {
+			if bracketed[j] || excluding || name != "" {
+				sources[j] = "[" + joinNonEmpty(captureSource(name), sources[j], exclusion) + "]"
+			}
+			if sources[j] != parts[i].patterns[j] {
+				parts[i].sources = sources
+			}
+		}
+		if repeat := parts[i].repeat; repeat != nil && (repeat.max == -1 || !canExpandRepetition(len(parts[i].patterns), *repeat)) {
+			if parts[i].segments != nil {
+				return parts, errors.New("invalid query: regular expressions, ranges, placeholders and predicates can not be repeated without a maximum or with too many sequences")
+			}
+			parts[i].segments = []segment{newRepeatSegment(parts[i].patterns, repeat.min, repeat.max, cfg.optionalQuestion)}
+			parts[i].patterns = []string{segmentMarker(segments)}
+			segments++
+		} else if repeat != nil {
+			parts[i].patterns = repeatAlternatives(parts[i].patterns, repeat.min, repeat.max)
+		}
+		if repeat := parts[i].repeat; repeat != nil {
+			// the repeated alternatives can not be told apart, so they share the source of the whole group
+			parts[i].sources = make([]string, len(parts[i].patterns))
+			for j := range parts[i].sources {
+				parts[i].sources[j] = "[ " + parts[i].source(0, len(parts[i].content)) + " ]" + repeat.String()
+			}
+		}
+		if name != "" {
+			open := &captureSegment{index: len(captures), name: name, typ: capturedType(parts[i])}
+			close := &captureSegment{index: len(captures), name: name, end: true}
+			for j := range parts[i].patterns {
+				parts[i].patterns[j] = segmentMarker(segments) + parts[i].patterns[j] + segmentMarker(segments+1)
+			}
+			parts[i].segments = append(parts[i].segments, open, close)
+			segments += 2
+			captures[name] = true
 		}
 	}
-	return parts
+	return parts, nil
+}
+
+// captureSource returns the prefix of a group capturing as name, an empty name returns an empty prefix
+func captureSource(name string) string {
+	if name == "" {
+		return ""
+	}
+	return ":" + name
+}
+
+// joinNonEmpty joins the texts which are not empty with single spaces
+func joinNonEmpty(texts ...string) string {
+	var joined []string
+	for _, text := range texts {
+		if text != "" {
+			joined = append(joined, text)
+		}
+	}
+	return strings.Join(joined, " ")
+}
+
+// cutCaptureName splits a group like [:id *] into the name of the capture and its content,
+// a group without a name like [:int] or [ * ] returns an empty name
+func cutCaptureName(content string) (string, string, error) {
+	if !strings.HasPrefix(content, ":") {
+		return "", content, nil
+	}
+	end := strings.IndexAny(content, " \t\r\n")
+	if end == -1 {
+		return "", content, nil
+	}
+	// a typed placeholder followed by more alternatives like [:ipv4 | localhost]
+	rest := strings.TrimSpace(content[end:])
+	if rest == "" || rest[0] == '|' || rest[0] == '!' {
+		return "", content, nil
+	}
+	name := content[1:end]
+	if !isIdentifier(name) {
+		return "", content, errors.New("invalid query: invalid capture name " + name)
+	}
+	return name, rest, nil
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// capturedType returns the name of the placeholder type if the only alternative of the part is a typed placeholder
+func capturedType(p part) string {
+	if len(p.patterns) != 1 || len(p.segments) != 1 || utf8.RuneCountInString(p.patterns[0]) != 1 {
+		return ""
+	}
+	if placeholder, ok := p.segments[0].(*placeholderSegment); ok {
+		return placeholder.name
+	}
+	return ""
+}
+
+// replaceSegments replaces every regular expression in the alternative with a segment marker,
+// an alternative that is a numeric range like 0..127 gets replaced as a whole.
+// The markers start at the given index
+func replaceSegments(alternative string, index int, cfg config) (string, []segment, error) {
+	if strings.HasPrefix(alternative, "@") {
+		seg, err := cfg.registry.newPredicateSegment(alternative[1:])
+		if err != nil {
+			return alternative, nil, err
+		}
+		return segmentMarker(index), []segment{seg}, nil
+	}
+	if strings.HasPrefix(alternative, ":") {
+		seg, ok := newPlaceholderSegment(alternative[1:])
+		if !ok {
+			return alternative, nil, errors.New("invalid query: unknown placeholder type " + alternative)
+		}
+		return segmentMarker(index), []segment{seg}, nil
+	}
+	if seg, ok, err := parseRange(alternative); ok || err != nil {
+		if err != nil {
+			return alternative, nil, err
+		}
+		return segmentMarker(index), []segment{seg}, nil
+	}
+	var segments []segment
+	for {
+		start := indexUnescaped(alternative, regexpOpen)
+		if start == -1 {
+			return alternative, segments, nil
+		}
+		end := regexpEnd(alternative, start)
+		if end == -1 {
+			return alternative, segments, errors.New("invalid query: expected '/' after regular expression")
+		}
+		seg, err := newRegexpSegment(alternative[start+len(regexpOpen) : end-1])
+		if err != nil {
+			return alternative, segments, err
+		}
+		alternative = alternative[:start] + segmentMarker(index+len(segments)) + alternative[end:]
+		segments = append(segments, seg)
+	}
+}
+
+// maxRepeatExpansion is the maximum amount of sequences a bounded repetition gets expanded into,
+// repetitions with more sequences are matched by an nfa like unbounded ones
+const maxRepeatExpansion = 256
+
+// canExpandRepetition reports if the sequences of min to max out of n alternatives stay below maxRepeatExpansion
+func canExpandRepetition(n int, repeat repetition) bool {
+	total, count := 0, 1
+	for i := 0; i <= repeat.max; i++ {
+		if i >= repeat.min {
+			total += count
+		}
+		if total > maxRepeatExpansion {
+			return false
+		}
+		if count *= n; count > maxRepeatExpansion {
+			count = maxRepeatExpansion + 1
+		}
+	}
+	return true
+}
+
+// repeatAlternatives returns every sequence of min to max alternatives
+func repeatAlternatives(alternatives []string, min int, max int) []string {
+	repeated := []string{}
+	for n := min; n <= max; n++ {
+		params := make([][]string, n)
+		for i := range params {
+			params[i] = alternatives
+		}
+		for _, sequence := range cartesian(params...) {
+			repeated = append(repeated, strings.Join(sequence, ""))
+		}
+	}
+	return repeated
+}
+
+func splitAlternatives(content string, cfg config) []string {
+	alternatives, _ := splitAlternativesAt(content, 0, cfg)
+	return alternatives
+}
+
+// splitAlternativesAt splits the content into its alternatives and returns their positions,
+// at is the position of the content
+func splitAlternativesAt(content string, at int, cfg config) ([]string, []int) {
+	alternatives, starts := []string{}, []int{}
+	for {
+		before, after, found := cutOutsideRegexp(content, '|')
+		alternative := cfg.trim(before)
+		alternatives = append(alternatives, alternative)
+		starts = append(starts, at+strings.Index(before, alternative))
+		if !found {
+			return alternatives, starts
+		}
+		content = after
+		at += len(before) + 1
+	}
+}
+
+// cutOutsideRegexp works like strings.Cut but ignores escaped separators and separators inside of regular expressions
+func cutOutsideRegexp(content string, sep byte) (string, string, bool) {
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(content[i:], regexpOpen) {
+			if end := regexpEnd(content, i); end != -1 {
+				i = end - 1
+				continue
+			}
+		}
+		if content[i] == sep {
+			return content[:i], content[i+1:], true
+		}
+	}
+	return content, "", false
+}
+
+// hasWildcard reports if a static part contains wildcards or escapes, those parts have to
+// go through the cartesian product so their wildcards do not get compared literally
+// and their escapes get removed
+func hasWildcard(p part) bool {
+	return strings.ContainsAny(p.content, "*?+\\")
 }
 
 func extractPrefixFromParts(parts []part) (string, []part) {
-	if len(parts) == 0 || !parts[0].static {
+	if len(parts) == 0 || !parts[0].static || hasWildcard(parts[0]) {
 		return "", parts
 	}
 	return parts[0].content, parts[1:]
 }
 
 func extractSuffixFromParts(parts []part) (string, []part) {
-	if len(parts) == 0 || !parts[len(parts)-1].static {
+	if len(parts) == 0 || !parts[len(parts)-1].static || hasWildcard(parts[len(parts)-1]) {
 		return "", parts
 	}
 	return parts[len(parts)-1].content, parts[0 : len(parts)-1]
@@ -106,45 +677,129 @@ func generateCartesianProduct(parts []part) [][]string {
 	return cartesian(permutable...)
 }
 
+// isWildcard reports if the rune of a product can not be compared literally
+func isWildcard(r rune) bool {
+	return r == '*' || r == '?' || r == '+' || isSegmentMarker(r)
+}
+
+// extractPrefixAndSuffixFromProduct splits the product around its wildcards, escaped wildcards
+// do not count and get unescaped in the prefix and suffix
 func extractPrefixAndSuffixFromProduct(data []string) (string, string, string) {
-	prefix := ""
-	suffix := ""
 	datastr := strings.Join(data, "")
-	for i, r := range datastr {
-		if r == '*' || r == '?' {
-			datastr = datastr[i:]
-			break
+	start, end := -1, -1
+	for i := 0; i < len(datastr); {
+		r, size := utf8.DecodeRuneInString(datastr[i:])
+		if r == '\\' {
+			_, escaped := utf8.DecodeRuneInString(datastr[i+size:])
+			i += size + escaped
+			continue
 		}
-		prefix += string(r)
+		if isWildcard(r) {
+			if start == -1 {
+				start = i
+			}
+			end = i + size
+		}
+		i += size
+	}
+	// the product does not contain any wildcard, everything is prefix
+	if start == -1 {
+		return unescape(datastr), "", ""
 	}
-	lendatastr := len(datastr)
-	for i := lendatastr - 1; i > 0; i-- {
-		r := datastr[i]
-		if r == '*' || r == '?' {
-			datastr = datastr[:i+1]
-			break
+	return unescape(datastr[:start]), datastr[start:end], unescape(datastr[end:])
+}
+
+// generateExclusions returns the products that get subtracted from the given product.
+// For every exclusion of a part the product gets copied with the exclusion in place of the parts alternative,
+// the columns of a product correspond to the parts it was generated from
+func generateExclusions(parts []part, product []string) [][]string {
+	exclusions := [][]string{}
+	for i, part := range parts {
+		for _, exclusion := range part.exclusions {
+			excluded := append([]string{}, product...)
+			excluded[i] = exclusion
+			exclusions = append(exclusions, excluded)
 		}
-		suffix = string(r) + suffix
 	}
-	return prefix, datastr, suffix
+	return exclusions
+}
+
+// collectSegments returns the segments of the parts in the order of their markers
+func collectSegments(parts []part) []segment {
+	var segments []segment
+	for _, part := range parts {
+		segments = append(segments, part.segments...)
+	}
+	return segments
 }
 
-func combineFixData(prefix string, suffix string, cartesianProduct [][]string) []prepared {
+func combineFixData(prefix string, suffix string, parts []part, cartesianProduct [][]string, cfg config) []prepared {
+	segments := collectSegments(parts)
 	preparedData := make([]prepared, len(cartesianProduct))
 	for i, product := range cartesianProduct {
-		p, pattern, s := extractPrefixAndSuffixFromProduct(product)
-		preparedData[i] = prepared{
-			prefix:          prefix + p,
-			prefixLen:       len(prefix + p),
-			pattern:         pattern,
-			suffix:          suffix + s,
-			suffixLen:       len(suffix + s),
-			advancedPattern: strings.Contains(pattern, "*"),
+		preparedData[i] = prepareProduct(prefix, suffix, product, segments, cfg)
+		preparedData[i].source = prefix + productSource(parts, product) + suffix
+		for _, exclusion := range generateExclusions(parts, product) {
+			preparedData[i].excludes = append(preparedData[i].excludes, prepareProduct(prefix, suffix, exclusion, segments, cfg))
 		}
 	}
 	return preparedData
 }
 
+// productSource joins the sources of the alternatives the product was generated from
+func productSource(parts []part, product []string) string {
+	source := strings.Builder{}
+	for i, part := range parts {
+		if part.static {
+			source.WriteString(part.source(0, len(part.content)))
+			continue
+		}
+		if part.sources == nil {
+			source.WriteString(product[i])
+			continue
+		}
+		for j, pattern := range part.patterns {
+			if pattern == product[i] {
+				source.WriteString(part.sources[j])
+				break
+			}
+		}
+	}
+	return source.String()
+}
+
+func prepareProduct(prefix string, suffix string, product []string, segments []segment, cfg config) prepared {
+	p, pattern, s := extractPrefixAndSuffixFromProduct(product)
+	usesSegments := indexSegmentMarker(pattern) != -1
+	if !usesSegments {
+		segments = nil
+	}
+	return prepared{
+		prefix:           prefix + p,
+		prefixLen:        len(prefix + p),
+		pattern:          pattern,
+		suffix:           s + suffix,
+		suffixLen:        len(s + suffix),
+		advancedPattern:  usesSegments || strings.ContainsAny(pattern, "*+"),
+		optionalQuestion: cfg.optionalQuestion && strings.Contains(pattern, "?"),
+		segments:         segments,
+	}
+}
+
+// unanchorPrepared moves the prefix and the suffix into the pattern and surrounds it with stars,
+// so the prepared matches anywhere inside of the data
+func unanchorPrepared(p prepared) prepared {
+	p.pattern = "*" + QuoteMeta(p.prefix) + p.pattern + QuoteMeta(p.suffix) + "*"
+	p.source = "*" + p.source + "*"
+	p.prefix, p.suffix = "", ""
+	p.prefixLen, p.suffixLen = 0, 0
+	p.advancedPattern = true
+	for i := range p.excludes {
+		p.excludes[i] = unanchorPrepared(p.excludes[i])
+	}
+	return p
+}
+
 func calculateComplexityOfPrepared(p prepared) int {
 	// in case the pattern is of length 0 or is * it ts pattern complexity is 0
 	patternComplexity := 0
@@ -193,9 +848,24 @@ func orderPreparedByComplexity(ps []prepared) []prepared {
 }
 
 func matchSingle(p prepared, data string, dataLen int) bool {
-	if !strings.HasPrefix(data, p.prefix) || !strings.HasSuffix(data, p.suffix) {
+	if !matchPrepared(p, data, dataLen) {
+		return false
+	}
+	for i := range p.excludes {
+		if matchPrepared(p.excludes[i], data, dataLen) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPrepared(p prepared, data string, dataLen int) bool {
+	if dataLen < p.prefixLen+p.suffixLen || !strings.HasPrefix(data, p.prefix) || !strings.HasSuffix(data, p.suffix) {
 		return false
 	}
+	if p.segments != nil || p.optionalQuestion {
+		return matchWildcard(p.pattern, data[p.prefixLen:dataLen-p.suffixLen], p.optionalQuestion, p.segments)
+	}
 	if p.advancedPattern {
 		return matchWildcardAdvanced(p.pattern, data[p.prefixLen:dataLen-p.suffixLen])
 	}
@@ -13,37 +13,42 @@ func TestParseQueryIntoParts(t *testing.T) {
 		{
 			static:  false,
 			content: "*",
+			offset:  2,
 		},
 		{
 			static:  true,
 			content: "test",
+			offset:  5,
 		},
 		{
 			static:  false,
 			content: "wild1 | wild2 | wil?4 | wi*ld",
+			offset:  11,
 		},
 		{
 			static:  true,
 			content: "next",
+			offset:  42,
 		},
 		{
 			static:  false,
 			content: "*",
+			offset:  48,
 		},
 	}
-	actual, err := parseQueryIntoParts(query)
+	actual, err := parseQueryIntoParts(query, config{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 
 	notParseableQuery := "test wild1 | wild2 | wil?4 | wi*ld ]next[ * ]"
-	_, err = parseQueryIntoParts(notParseableQuery)
+	_, err = parseQueryIntoParts(notParseableQuery, config{})
 	assert.Error(t, err)
 }
 
 func TestParsePatterns(t *testing.T) {
 	query := "test[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]"
-	parts, err := parseQueryIntoParts(query)
+	parts, err := parseQueryIntoParts(query, config{})
 	assert.NoError(t, err)
 
 	expected := []part{
@@ -54,6 +59,7 @@ func TestParsePatterns(t *testing.T) {
 		{
 			static:  false,
 			content: "wild1 | wild2 | wil?4 | wi*ld",
+			offset:  6,
 			patterns: []string{
 				"wild1",
 				"wild2",
@@ -64,24 +70,28 @@ func TestParsePatterns(t *testing.T) {
 		{
 			static:  true,
 			content: "next",
+			offset:  37,
 		},
 		{
 			static:   false,
 			content:  "*",
+			offset:   43,
 			patterns: []string{"*"},
 		},
 	}
 
-	actual := parsePatterns(parts)
+	actual, err := parsePatterns(parts, config{})
+	assert.NoError(t, err)
 	assert.Equal(t, expected, actual)
 }
 
 func TestExtractPreAndSuffix(t *testing.T) {
 	{
 		query := "test[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]suff"
-		parts, err := parseQueryIntoParts(query)
+		parts, err := parseQueryIntoParts(query, config{})
+		assert.NoError(t, err)
+		parts, err = parsePatterns(parts, config{})
 		assert.NoError(t, err)
-		parts = parsePatterns(parts)
 
 		pre, rest, suf := extractPreAndSuffixFromParts(parts)
 
@@ -90,6 +100,7 @@ func TestExtractPreAndSuffix(t *testing.T) {
 			{
 				static:  false,
 				content: "wild1 | wild2 | wil?4 | wi*ld",
+				offset:  6,
 				patterns: []string{
 					"wild1",
 					"wild2",
@@ -100,10 +111,12 @@ func TestExtractPreAndSuffix(t *testing.T) {
 			{
 				static:  true,
 				content: "next",
+				offset:  37,
 			},
 			{
 				static:   false,
 				content:  "*",
+				offset:   43,
 				patterns: []string{"*"},
 			},
 		}, rest)
@@ -111,9 +124,10 @@ func TestExtractPreAndSuffix(t *testing.T) {
 	}
 	{
 		query := "[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]"
-		parts, err := parseQueryIntoParts(query)
+		parts, err := parseQueryIntoParts(query, config{})
+		assert.NoError(t, err)
+		parts, err = parsePatterns(parts, config{})
 		assert.NoError(t, err)
-		parts = parsePatterns(parts)
 
 		pre, rest, suf := extractPreAndSuffixFromParts(parts)
 
@@ -122,6 +136,7 @@ func TestExtractPreAndSuffix(t *testing.T) {
 			{
 				static:  false,
 				content: "wild1 | wild2 | wil?4 | wi*ld",
+				offset:  2,
 				patterns: []string{
 					"wild1",
 					"wild2",
@@ -132,10 +147,12 @@ func TestExtractPreAndSuffix(t *testing.T) {
 			{
 				static:  true,
 				content: "next",
+				offset:  33,
 			},
 			{
 				static:   false,
 				content:  "*",
+				offset:   39,
 				patterns: []string{"*"},
 			},
 		}, rest)
@@ -146,9 +163,10 @@ func TestExtractPreAndSuffix(t *testing.T) {
 
 func TestGenerateCartesianProduct(t *testing.T) {
 	query := "test[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]"
-	parts, err := parseQueryIntoParts(query)
+	parts, err := parseQueryIntoParts(query, config{})
+	assert.NoError(t, err)
+	parts, err = parsePatterns(parts, config{})
 	assert.NoError(t, err)
-	parts = parsePatterns(parts)
 	_, rest, _ := extractPreAndSuffixFromParts(parts)
 
 	actual := generateCartesianProduct(rest)
@@ -211,14 +229,16 @@ func TestExtractPrefixAndSuffixFromProduct(t *testing.T) {
 
 func TestCombineFixData(t *testing.T) {
 	query := "test[ wild1 | wild2 | wil?4 | wi*ld ]next[ * ]"
-	parts, err := parseQueryIntoParts(query)
+	parts, err := parseQueryIntoParts(query, config{})
+	assert.NoError(t, err)
+	parts, err = parsePatterns(parts, config{})
 	assert.NoError(t, err)
-	parts = parsePatterns(parts)
 	prefix, rest, suffix := extractPreAndSuffixFromParts(parts)
 	product := generateCartesianProduct(rest)
-	actual := combineFixData(prefix, suffix, product)
+	actual := combineFixData(prefix, suffix, rest, product, config{})
 	expected := []prepared{
 		{
+			source:          "testwild1next*",
 			prefix:          "testwild1next",
 			prefixLen:       len("testwild1next"),
 			pattern:         "*",
@@ -227,6 +247,7 @@ func TestCombineFixData(t *testing.T) {
 			advancedPattern: true,
 		},
 		{
+			source:          "testwild2next*",
 			prefix:          "testwild2next",
 			prefixLen:       len("testwild2next"),
 			pattern:         "*",
@@ -235,6 +256,7 @@ func TestCombineFixData(t *testing.T) {
 			advancedPattern: true,
 		},
 		{
+			source:          "testwil?4next*",
 			prefix:          "testwil",
 			prefixLen:       len("testwil"),
 			pattern:         "?4next*",
@@ -243,6 +265,7 @@ func TestCombineFixData(t *testing.T) {
 			advancedPattern: true,
 		},
 		{
+			source:          "testwi*ldnext*",
 			prefix:          "testwi",
 			prefixLen:       len("testwi"),
 			pattern:         "*ldnext*",
@@ -398,3 +421,248 @@ func TestMatchMulti(t *testing.T) {
 	assert.Equal(t, true, matchMulti(ps, "testwild1nextblablabla"))
 	assert.Equal(t, false, matchMulti(ps, "test1wild1nextblablabla"))
 }
+
+func TestExtractPrefixAndSuffixFromStaticProduct(t *testing.T) {
+	pre, rest, suf := extractPrefixAndSuffixFromProduct([]string{"namespace.", "real", ".value"})
+	assert.Equal(t, "namespace.real.value", pre)
+	assert.Equal(t, "", rest)
+	assert.Equal(t, "", suf)
+
+	p := prepared{
+		prefix:    "ab",
+		prefixLen: 2,
+		pattern:   "*",
+		suffix:    "ba",
+		suffixLen: 2,
+	}
+	assert.Equal(t, false, matchSingle(p, "aba", 3))
+	assert.Equal(t, true, matchSingle(p, "abba", 4))
+}
+
+func TestExtractPreAndSuffixWithWildcards(t *testing.T) {
+	parts, err := parseQueryIntoParts("legacy.*", config{})
+	assert.NoError(t, err)
+	parts, err = parsePatterns(parts, config{})
+	assert.NoError(t, err)
+
+	pre, rest, suf := extractPreAndSuffixFromParts(parts)
+	assert.Equal(t, "", pre)
+	assert.Equal(t, []part{{static: true, content: "legacy.*"}}, rest)
+	assert.Equal(t, "", suf)
+
+	ps := combineFixData(pre, suf, rest, generateCartesianProduct(rest), config{})
+	assert.Equal(t, []prepared{{
+		source:          "legacy.*",
+		prefix:          "legacy.",
+		prefixLen:       len("legacy."),
+		pattern:         "*",
+		advancedPattern: true,
+	}}, ps)
+}
+
+func TestParsePatternsWithExclusions(t *testing.T) {
+	parts, err := parseQueryIntoParts("logs.[ * ! debug | trace ].out[ ! tmp ]", config{})
+	assert.NoError(t, err)
+
+	actual, err := parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	assert.Equal(t, []part{
+		{
+			static:  true,
+			content: "logs.",
+		},
+		{
+			static:     false,
+			content:    "* ! debug | trace",
+			offset:     7,
+			patterns:   []string{"*"},
+			exclusions: []string{"debug", "trace"},
+			sources:    []string{"[* ! debug | trace]"},
+		},
+		{
+			static:  true,
+			content: ".out",
+			offset:  26,
+		},
+		{
+			static:     false,
+			content:    "! tmp",
+			offset:     32,
+			patterns:   []string{"*"},
+			exclusions: []string{"tmp"},
+			sources:    []string{"[! tmp]"},
+		},
+	}, actual)
+}
+
+func TestCombineFixDataWithExclusions(t *testing.T) {
+	parts, err := parseQueryIntoParts("logs.[ * ! debug | trace ].out", config{})
+	assert.NoError(t, err)
+	parts, err = parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	prefix, rest, suffix := extractPreAndSuffixFromParts(parts)
+	actual := combineFixData(prefix, suffix, rest, generateCartesianProduct(rest), config{})
+
+	assert.Equal(t, []prepared{
+		{
+			source:          "logs.[* ! debug | trace].out",
+			prefix:          "logs.",
+			prefixLen:       len("logs."),
+			pattern:         "*",
+			suffix:          ".out",
+			suffixLen:       len(".out"),
+			advancedPattern: true,
+			excludes: []prepared{
+				{
+					prefix:    "logs.debug",
+					prefixLen: len("logs.debug"),
+					suffix:    ".out",
+					suffixLen: len(".out"),
+				},
+				{
+					prefix:    "logs.trace",
+					prefixLen: len("logs.trace"),
+					suffix:    ".out",
+					suffixLen: len(".out"),
+				},
+			},
+		},
+	}, actual)
+}
+
+func TestParseRepetition(t *testing.T) {
+	testCases := []struct {
+		rest   string
+		repeat *repetition
+		n      int
+		err    bool
+	}{
+		{rest: "", repeat: nil, n: 0},
+		{rest: ".value", repeat: nil, n: 0},
+		{rest: "+.value", repeat: &repetition{min: 1, max: -1}, n: 1},
+		{rest: "*", repeat: &repetition{min: 0, max: -1}, n: 1},
+		{rest: "?", repeat: &repetition{min: 0, max: 1}, n: 1},
+		{rest: "{3}x", repeat: &repetition{min: 3, max: 3}, n: 3},
+		{rest: "{1,3}", repeat: &repetition{min: 1, max: 3}, n: 5},
+		{rest: "{2,}", repeat: &repetition{min: 2, max: -1}, n: 4},
+		{rest: "{,2}", repeat: &repetition{min: 0, max: 2}, n: 4},
+		{rest: "{3,1}", err: true},
+		{rest: "{a}", err: true},
+		{rest: "{1,3", err: true},
+		{rest: "{1,1001}", err: true},
+	}
+	for _, testCase := range testCases {
+		repeat, n, err := parseRepetition(testCase.rest)
+		if testCase.err {
+			assert.Error(t, err, testCase.rest)
+			continue
+		}
+		assert.NoError(t, err, testCase.rest)
+		assert.Equal(t, testCase.repeat, repeat, testCase.rest)
+		assert.Equal(t, testCase.n, n, testCase.rest)
+	}
+}
+
+func TestParsePatternsWithRepetitions(t *testing.T) {
+	parts, err := parseQueryIntoParts("[ a | b ]{1,2}.[ x ]?[ seg. ]+end", config{})
+	assert.NoError(t, err)
+
+	actual, err := parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	assert.Len(t, actual, 6)
+	assert.ElementsMatch(t, []string{"a", "b", "aa", "ab", "ba", "bb"}, actual[0].patterns)
+	assert.Equal(t, ".", actual[1].content)
+	assert.Equal(t, []string{"", "x"}, actual[2].patterns)
+	assert.Equal(t, []string{segmentMarker(0)}, actual[4].patterns)
+	assert.Equal(t, []segment{newRepeatSegment([]string{"seg."}, 1, -1, false)}, actual[4].segments)
+	assert.Equal(t, "end", actual[5].content)
+}
+
+func TestParsePatternsWithLargeRepetitions(t *testing.T) {
+	assert.True(t, canExpandRepetition(2, repetition{min: 1, max: 7}))
+	assert.False(t, canExpandRepetition(4, repetition{min: 1, max: 9}))
+	assert.False(t, canExpandRepetition(2, repetition{min: 0, max: maxRepetition}))
+
+	parts, err := parseQueryIntoParts("[ a | b | c | d ]{1,9}", config{})
+	assert.NoError(t, err)
+
+	actual, err := parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{segmentMarker(0)}, actual[0].patterns)
+	assert.Equal(t, []segment{newRepeatSegment([]string{"a", "b", "c", "d"}, 1, 9, false)}, actual[0].segments)
+
+	prepared, err := compilePrepared("[ a | b | c | d ]{1,9}", config{})
+	assert.NoError(t, err)
+	assert.Len(t, prepared, 1)
+	assert.True(t, matchMulti(prepared, "a"))
+	assert.True(t, matchMulti(prepared, "abcdabcda"))
+	assert.False(t, matchMulti(prepared, ""))
+	assert.False(t, matchMulti(prepared, "abcdabcdab"))
+	assert.False(t, matchMulti(prepared, "abe"))
+
+	parts, err = parseQueryIntoParts("[ a | /re:[0-9]/ ]{1,9}", config{})
+	assert.NoError(t, err)
+	_, err = parsePatterns(parts, config{})
+	assert.Error(t, err)
+}
+
+func TestParsePatternsWithRegexps(t *testing.T) {
+	parts, err := parseQueryIntoParts("id.[ /re:[0-9a-f]{4}|x\\/y/ | v/re:\\d+/ ! /re:0+/ ].end", config{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/re:[0-9a-f]{4}|x\\/y/ | v/re:\\d+/ ! /re:0+/", parts[1].content)
+
+	actual, err := parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{segmentMarker(0), "v" + segmentMarker(1)}, actual[1].patterns)
+	assert.Equal(t, []string{segmentMarker(2)}, actual[1].exclusions)
+	assert.Len(t, actual[1].segments, 3)
+	assert.Equal(t, true, actual[1].segments[0].matches("ab09"))
+	assert.Equal(t, true, actual[1].segments[0].matches("x/y"))
+	assert.Equal(t, false, actual[1].segments[0].matches("ab09x"))
+
+	parts, err = parseQueryIntoParts("id.[ /re:(/ ]", config{})
+	assert.NoError(t, err)
+	_, err = parsePatterns(parts, config{})
+	assert.Error(t, err)
+
+	_, err = parseQueryIntoParts("id.[ /re:[0-9] ]", config{})
+	assert.Error(t, err)
+}
+
+func TestCombineFixDataWithRegexps(t *testing.T) {
+	parts, err := parseQueryIntoParts("users.[ /re:[0-9]+/ ].profile", config{})
+	assert.NoError(t, err)
+	parts, err = parsePatterns(parts, config{})
+	assert.NoError(t, err)
+	prefix, rest, suffix := extractPreAndSuffixFromParts(parts)
+	actual := combineFixData(prefix, suffix, rest, generateCartesianProduct(rest), config{})
+
+	assert.Len(t, actual, 1)
+	assert.Equal(t, "users.", actual[0].prefix)
+	assert.Equal(t, segmentMarker(0), actual[0].pattern)
+	assert.Equal(t, ".profile", actual[0].suffix)
+	assert.Equal(t, rest[0].segments, actual[0].segments)
+}
+
+func TestCutCaptureName(t *testing.T) {
+	testCases := []struct {
+		content string
+		name    string
+		rest    string
+	}{
+		{content: ":id *", name: "id", rest: "*"},
+		{content: ":port   :int", name: "port", rest: ":int"},
+		{content: ":int", name: "", rest: ":int"},
+		{content: ":ipv4 | localhost", name: "", rest: ":ipv4 | localhost"},
+		{content: "a | b", name: "", rest: "a | b"},
+	}
+	for _, testCase := range testCases {
+		name, rest, err := cutCaptureName(testCase.content)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.name, name, testCase.content)
+		assert.Equal(t, testCase.rest, rest, testCase.content)
+	}
+
+	_, _, err := cutCaptureName(":user-id *")
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+package match
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// metaRunes are the runes that have a meaning in patterns or expressions
+const metaRunes = `\*?+[]{}|!@:/.()&"#`
+
+// QuoteMeta returns a pattern that matches exactly the given text, every rune with a meaning in patterns,
+// every whitespace and every rune of the segment marker range gets escaped with a backslash
+func QuoteMeta(text string) string {
+	quoted := strings.Builder{}
+	for _, r := range text {
+		if strings.ContainsRune(metaRunes, r) || unicode.IsSpace(r) || isSegmentMarker(r) {
+			quoted.WriteByte('\\')
+		}
+		quoted.WriteRune(r)
+	}
+	return quoted.String()
+}
+
+// unescape removes the backslashes in front of escaped runes
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	unescaped := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			if i == len(s) {
+				break
+			}
+		}
+		unescaped.WriteByte(s[i])
+	}
+	return unescaped.String()
+}
+
+// indexUnescaped works like strings.Index but ignores escaped occurrences of substr
+func indexUnescaped(s string, substr string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(s[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// trimSpace works like strings.TrimSpace but keeps an escaped trailing whitespace
+func trimSpace(s string) string {
+	trimmed := strings.TrimSpace(s)
+	backslashes := len(trimmed) - len(strings.TrimRight(trimmed, `\`))
+	if backslashes%2 == 0 {
+		return trimmed
+	}
+	rest := strings.TrimLeftFunc(s, unicode.IsSpace)[len(trimmed):]
+	_, size := utf8.DecodeRuneInString(rest)
+	return trimmed + rest[:size]
+}
+
+// quoteEnd returns the index after the closing '"' of the quoted literal starting at start,
+// or -1 if the quoted literal is not closed
+func quoteEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
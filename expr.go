@@ -0,0 +1,310 @@
+package match
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExprError describes an invalid expression and the byte position in the expression where the error occurred
+type ExprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("invalid expression at position %d: %s", e.Pos, e.Msg)
+}
+
+type exprTokenKind int
+
+const (
+	exprPattern exprTokenKind = iota
+	exprAnd
+	exprOr
+	exprNot
+	exprOpen
+	exprClose
+	exprEnd
+)
+
+type exprToken struct {
+	kind    exprTokenKind
+	pos     int
+	content string
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	opts   []Option
+}
+
+type prefixGuard struct {
+	prefix string
+	m      Matcher
+}
+
+// CompileExpr compiles a boolean expression over patterns into a single matcher
+//
+// # An expression can look like the following:
+//
+// service.[ api | web ].* && !*.debug || legacy.*
+//
+// Operands are patterns as accepted by Compile, they can be combined with
+// && (and), || (or), ! (not) and grouped with parentheses. && binds stronger than ||.
+// Whitespace outside of brackets separates operands and operators.
+// The options get applied to every operand
+func CompileExpr(expr string, opts ...Option) (Matcher, error) {
+	tokens, err := tokenizeExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := exprParser{tokens: tokens, opts: opts}
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != exprEnd {
+		return nil, &ExprError{Pos: t.pos, Msg: "unexpected " + t.describe()}
+	}
+	return m, nil
+}
+
+func tokenizeExpr(expr string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	i := 0
+	for i < len(expr) {
+		switch {
+		case expr[i] == ' ' || expr[i] == '\t' || expr[i] == '\n' || expr[i] == '\r':
+			i++
+		case strings.HasPrefix(expr[i:], "&&"):
+			tokens = append(tokens, exprToken{kind: exprAnd, pos: i})
+			i += 2
+		case strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, exprToken{kind: exprOr, pos: i})
+			i += 2
+		case expr[i] == '!':
+			tokens = append(tokens, exprToken{kind: exprNot, pos: i})
+			i++
+		case expr[i] == '(':
+			tokens = append(tokens, exprToken{kind: exprOpen, pos: i})
+			i++
+		case expr[i] == ')':
+			tokens = append(tokens, exprToken{kind: exprClose, pos: i})
+			i++
+		default:
+			end, err := scanExprPattern(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, exprToken{kind: exprPattern, pos: i, content: expr[i:end]})
+			i = end
+		}
+	}
+	return append(tokens, exprToken{kind: exprEnd, pos: len(expr)}), nil
+}
+
+// scanExprPattern returns the end of the pattern starting at start,
+// everything inside brackets belongs to the pattern
+func scanExprPattern(expr string, start int) (int, error) {
+	open := -1
+	for i := start; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '"' {
+			if end := quoteEnd(expr, i); end != -1 {
+				i = end - 1
+				continue
+			}
+		}
+		if open != -1 && strings.HasPrefix(expr[i:], regexpOpen) {
+			if end := regexpEnd(expr, i); end != -1 {
+				i = end - 1
+				continue
+			}
+		}
+		if open != -1 {
+			if c == ']' {
+				open = -1
+			}
+			continue
+		}
+		switch {
+		case c == '[':
+			open = i
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(' || c == ')':
+			return i, nil
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			return i, nil
+		}
+	}
+	if open != -1 {
+		return 0, &ExprError{Pos: open, Msg: "expected ']' after '['"}
+	}
+	return len(expr), nil
+}
+
+func (t exprToken) describe() string {
+	switch t.kind {
+	case exprAnd:
+		return "'&&'"
+	case exprOr:
+		return "'||'"
+	case exprNot:
+		return "'!'"
+	case exprOpen:
+		return "'('"
+	case exprClose:
+		return "')'"
+	case exprEnd:
+		return "end of expression"
+	}
+	return "pattern " + t.content
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != exprEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) parseOr() (Matcher, error) {
+	m, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	ms := []Matcher{m}
+	for p.peek().kind == exprOr {
+		p.next()
+		m, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	if len(ms) == 1 {
+		return ms[0], nil
+	}
+	return guardPrefix(Or(ms...)), nil
+}
+
+func (p *exprParser) parseAnd() (Matcher, error) {
+	m, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	ms := []Matcher{m}
+	for p.peek().kind == exprAnd {
+		p.next()
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	if len(ms) == 1 {
+		return ms[0], nil
+	}
+	return guardPrefix(And(ms...)), nil
+}
+
+func (p *exprParser) parseUnary() (Matcher, error) {
+	t := p.next()
+	switch t.kind {
+	case exprNot:
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(m), nil
+	case exprOpen:
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); c.kind != exprClose {
+			return nil, &ExprError{Pos: c.pos, Msg: "expected ')' but got " + c.describe()}
+		}
+		return m, nil
+	case exprPattern:
+		m, err := Compile(t.content, p.opts...)
+		if patternErr, ok := err.(*PatternError); ok {
+			return nil, &ExprError{Pos: t.pos + patternErr.Pos, Msg: patternErr.Msg}
+		}
+		if err != nil {
+			return nil, &ExprError{Pos: t.pos, Msg: err.Error()}
+		}
+		return m, nil
+	}
+	return nil, &ExprError{Pos: t.pos, Msg: "expected pattern but got " + t.describe()}
+}
+
+// guardPrefix factors the static prefix every match of m has to start with
+// out of its operands, so data that does not start with it gets rejected with a single comparison
+func guardPrefix(m Matcher) Matcher {
+	if _, ok := m.(matcher); ok {
+		return m
+	}
+	prefix := requiredPrefix(m)
+	if prefix == "" {
+		return m
+	}
+	return prefixGuard{prefix: prefix, m: m}
+}
+
+// requiredPrefix returns a static prefix every string matched by m starts with
+func requiredPrefix(m Matcher) string {
+	switch m := m.(type) {
+	case matcher:
+		if len(m.prepared) == 0 {
+			return ""
+		}
+		prefix := m.prepared[0].prefix
+		for _, p := range m.prepared[1:] {
+			prefix = commonPrefix(prefix, p.prefix)
+		}
+		return prefix
+	case prefixGuard:
+		return m.prefix
+	case orMatcher:
+		if len(m) == 0 {
+			return ""
+		}
+		prefix := requiredPrefix(m[0])
+		for _, o := range m[1:] {
+			prefix = commonPrefix(prefix, requiredPrefix(o))
+		}
+		return prefix
+	case andMatcher:
+		// every operand has to match, so the longest required prefix applies
+		prefix := ""
+		for _, o := range m {
+			if p := requiredPrefix(o); len(p) > len(prefix) {
+				prefix = p
+			}
+		}
+		return prefix
+	}
+	return ""
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+func (g prefixGuard) Matches(data string) bool {
+	return strings.HasPrefix(data, g.prefix) && g.m.Matches(data)
+}
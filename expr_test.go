@@ -0,0 +1,67 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileExpr(t *testing.T) {
+	m, err := CompileExpr("service.[api|web].* && !*.debug || legacy.*")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("service.api.users"))
+	assert.Equal(t, true, m.Matches("service.web.index"))
+	assert.Equal(t, true, m.Matches("legacy.debug"))
+	assert.Equal(t, false, m.Matches("service.api.debug"))
+	assert.Equal(t, false, m.Matches("service.db.users"))
+	assert.Equal(t, false, m.Matches("other"))
+
+	m, err = CompileExpr("!(a.* || b.*) && [ * ].txt")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("c.txt"))
+	assert.Equal(t, false, m.Matches("a.txt"))
+	assert.Equal(t, false, m.Matches("c.md"))
+
+	m, err = CompileExpr("ids.[ /re:[0-9]+ [a-z]+/ ] && !ids.[ /re:0+ .*/ ]")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("ids.12 ab"))
+	assert.Equal(t, false, m.Matches("ids.00 ab"))
+
+	m, err = CompileExpr("namespace.[ real | virtual ].[ root* ].value")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("namespace.real.root.path.value"))
+}
+
+func TestCompileExprErrors(t *testing.T) {
+	testCases := []struct {
+		expr string
+		pos  int
+	}{
+		{expr: "", pos: 0},
+		{expr: "a.* &&", pos: 6},
+		{expr: "(a.* || b.*", pos: 11},
+		{expr: "a.* b.*", pos: 4},
+		{expr: "a.* || ) ", pos: 7},
+		{expr: "a.* || [ b | c", pos: 7},
+		{expr: "a.* || b ] c", pos: 9},
+	}
+	for _, testCase := range testCases {
+		_, err := CompileExpr(testCase.expr)
+		exprErr, ok := err.(*ExprError)
+		if assert.True(t, ok, testCase.expr) {
+			assert.Equal(t, testCase.pos, exprErr.Pos, testCase.expr)
+		}
+	}
+}
+
+func TestGuardPrefix(t *testing.T) {
+	m, err := CompileExpr("service.api.* && !service.api.debug.*")
+	assert.NoError(t, err)
+	guard, ok := m.(prefixGuard)
+	assert.True(t, ok)
+	assert.Equal(t, "service.api.", guard.prefix)
+	assert.Equal(t, true, m.Matches("service.api.users"))
+	assert.Equal(t, false, m.Matches("service.api.debug.x"))
+	assert.Equal(t, false, m.Matches("service.web.users"))
+}
@@ -0,0 +1,58 @@
+package match_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedSyntax(t *testing.T) {
+	pattern := `
+		# services that are exposed publicly
+		svc.[
+			api |   # the public api
+			web |
+			"admin ui"
+		].[ /re:v[0-9]+ # x/ | \#latest ]
+	`
+	for _, m := range []match.Matcher{
+		mustCompilePattern(t, pattern, match.WithExtendedSyntax()),
+		mustCompilePattern(t, "(?x)"+pattern),
+	} {
+		assert.Equal(t, true, m.Matches("svc.api.v2 # x"))
+		assert.Equal(t, true, m.Matches("svc.admin ui.#latest"))
+		assert.Equal(t, false, m.Matches("svc.adminui.#latest"))
+		assert.Equal(t, false, m.Matches("svc.db.#latest"))
+	}
+
+	m := mustCompilePattern(t, "(?x)"+match.QuoteMeta("a #b c"))
+	assert.Equal(t, true, m.Matches("a #b c"))
+}
+
+func TestExtendedSyntaxErrors(t *testing.T) {
+	// the positions point into the pattern as it was written
+	for _, pattern := range []string{
+		"(?x)\n  a.\n  [@missing]",
+		"(?x) a. # [@commented]\n [ b | @missing ]",
+		"  a . [@missing]",
+	} {
+		_, err := match.Compile(pattern, match.WithExtendedSyntax())
+		patternErr, ok := err.(*match.PatternError)
+		assert.True(t, ok, pattern)
+		assert.Equal(t, strings.Index(pattern, "@missing"), patternErr.Pos, pattern)
+	}
+
+	lib := match.NewLibrary()
+	_, err := match.Compile("(?x) svc . @missing", match.WithLibrary(lib))
+	patternErr, ok := err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 11, patternErr.Pos)
+}
+
+func mustCompilePattern(t *testing.T, pattern string, opts ...match.Option) match.Matcher {
+	m, err := match.Compile(pattern, opts...)
+	assert.NoError(t, err)
+	return m
+}
@@ -0,0 +1,55 @@
+package match
+
+import "iter"
+
+// Filter returns the strings of data that are matched by m, in their original order
+func Filter(m Matcher, data []string) []string {
+	return FilterFunc(m, data, func(s string) string { return s })
+}
+
+// FilterFunc returns the items whose key is matched by m, in their original order
+func FilterFunc[T any](m Matcher, items []T, key func(T) string) []T {
+	filtered := []T{}
+	for _, item := range items {
+		if m.Matches(key(item)) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// FilterMapKeys returns a new map with the entries whose key is matched by m
+func FilterMapKeys[V any](m Matcher, items map[string]V) map[string]V {
+	filtered := map[string]V{}
+	for k, v := range items {
+		if m.Matches(k) {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
+
+// Partition splits data into the strings that are matched by m and the ones that are not,
+// both keep their original order
+func Partition(m Matcher, data []string) ([]string, []string) {
+	matched, unmatched := []string{}, []string{}
+	for _, s := range data {
+		if m.Matches(s) {
+			matched = append(matched, s)
+		} else {
+			unmatched = append(unmatched, s)
+		}
+	}
+	return matched, unmatched
+}
+
+// Select returns a sequence of the strings of seq that are matched by m
+func Select(m Matcher, seq iter.Seq[string]) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for s := range seq {
+			if m.Matches(s) && !yield(s) {
+				return
+			}
+		}
+	}
+}
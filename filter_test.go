@@ -0,0 +1,43 @@
+package match_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	m, err := match.Compile("svc.[ api | web ].*")
+	assert.NoError(t, err)
+	data := []string{"svc.api.eu", "svc.db.eu", "svc.web.us", "other"}
+
+	assert.Equal(t, []string{"svc.api.eu", "svc.web.us"}, match.Filter(m, data))
+	assert.Equal(t, []string{}, match.Filter(m, nil))
+
+	matched, unmatched := match.Partition(m, data)
+	assert.Equal(t, []string{"svc.api.eu", "svc.web.us"}, matched)
+	assert.Equal(t, []string{"svc.db.eu", "other"}, unmatched)
+
+	assert.Equal(t, []string{"svc.api.eu", "svc.web.us"}, slices.Collect(match.Select(m, slices.Values(data))))
+	for s := range match.Select(m, slices.Values(data)) {
+		assert.Equal(t, "svc.api.eu", s)
+		break
+	}
+}
+
+func TestFilterFunc(t *testing.T) {
+	type service struct {
+		name string
+		port int
+	}
+	m, err := match.Compile("svc.[ api | web ].*")
+	assert.NoError(t, err)
+	services := []service{{"svc.api.eu", 80}, {"svc.db.eu", 5432}, {"svc.web.us", 443}}
+
+	filtered := match.FilterFunc(match.Not(m), services, func(s service) string { return s.name })
+	assert.Equal(t, []service{{"svc.db.eu", 5432}}, filtered)
+
+	assert.Equal(t, map[string]int{"svc.api.eu": 1}, match.FilterMapKeys(m, map[string]int{"svc.api.eu": 1, "svc.db.eu": 2}))
+}
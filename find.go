@@ -0,0 +1,242 @@
+package match
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// shortestMatcher makes the find functions prefer the shortest match
+type shortestMatcher struct {
+	m Matcher
+}
+
+// Shortest returns a matcher that matches the same data as m but makes FindIndex and
+// the other search functions pick the shortest match at a position instead of the longest one
+func Shortest(m Matcher) Matcher {
+	if s, ok := m.(shortestMatcher); ok {
+		return s
+	}
+	return shortestMatcher{m: m}
+}
+
+func (s shortestMatcher) Matches(data string) bool {
+	return s.m.Matches(data)
+}
+
+func (s shortestMatcher) capture(data string) (Groups, bool) {
+	return Capture(s.m, data)
+}
+
+// FindIndex returns the byte offsets of the leftmost part of the text that is matched by m,
+// or nil if no part matches.
+//
+// # The matches are leftmost-longest:
+//
+// of all matches the one with the smallest start wins, and of all matches at that start
+// the longest one, so * consumes as much as it can. Wrap the matcher with Shortest to get the shortest one instead.
+//
+// # Cost
+//
+// Matchers that NewStream accepts are searched with an automaton in a single pass over the text.
+// All other matchers, like the ones with regular expressions or predicates, get matched against
+// every part of the text after every start offset, which gets slow on long texts
+func FindIndex(m Matcher, text string) []int {
+	start, end := newFinder(m).findAt(text, 0)
+	if start == -1 {
+		return nil
+	}
+	return []int{start, end}
+}
+
+// FindAllIndex returns the byte offsets of the successive non overlapping matches of m in the text,
+// n limits the amount of matches, a negative n returns all of them.
+// Like in the regexp package empty matches directly after a previous match get ignored
+func FindAllIndex(m Matcher, text string, n int) [][]int {
+	var matches [][]int
+	f := newFinder(m)
+	prevEnd := -1
+	for from := 0; from <= len(text) && (n < 0 || len(matches) < n); {
+		start, end := f.findAt(text, from)
+		if start == -1 {
+			break
+		}
+		if start == end && start == prevEnd {
+			from = nextRune(text, start)
+			continue
+		}
+		matches = append(matches, []int{start, end})
+		prevEnd = end
+		if start == end {
+			from = nextRune(text, end)
+		} else {
+			from = end
+		}
+	}
+	return matches
+}
+
+// FindAllString returns the successive non overlapping matches of m in the text, see FindAllIndex
+func FindAllString(m Matcher, text string, n int) []string {
+	var matches []string
+	for _, match := range FindAllIndex(m, text, n) {
+		matches = append(matches, text[match[0]:match[1]])
+	}
+	return matches
+}
+
+// Split slices the text into the parts between the matches of m, it works like Split of the regexp package.
+// n limits the amount of parts, the last part is the unsplit rest. A negative n returns all parts and n == 0 returns nil
+func Split(m Matcher, text string, n int) []string {
+	if n == 0 {
+		return nil
+	}
+	if text == "" {
+		return []string{""}
+	}
+	parts := []string{}
+	start, end := 0, 0
+	for _, match := range FindAllIndex(m, text, n) {
+		if n > 0 && len(parts) == n-1 {
+			break
+		}
+		end = match[0]
+		if match[1] != 0 {
+			parts = append(parts, text[start:end])
+		}
+		start = match[1]
+	}
+	if end != len(text) {
+		parts = append(parts, text[start:])
+	}
+	return parts
+}
+
+// finder searches the matches of a matcher, it holds everything that can be reused between searches in the same text
+type finder struct {
+	m        Matcher
+	shortest bool
+	prefix   string
+
+	// nfa is only set if the matcher can be compiled into an automaton,
+	// starts hold the start offset of the match every active state belongs to
+	nfa           *nfa
+	current, next *stateSet
+	starts, nexts []int
+}
+
+func newFinder(m Matcher) *finder {
+	f := &finder{}
+	if s, ok := m.(shortestMatcher); ok {
+		m, f.shortest = s.m, true
+	}
+	f.m = m
+	f.prefix = requiredPrefix(m)
+	if n, err := compileMatcherNFA(m); err == nil {
+		f.nfa = n
+		f.current, f.next = newStateSet(len(n.insts)), newStateSet(len(n.insts))
+		f.starts, f.nexts = make([]int, len(n.insts)), make([]int, len(n.insts))
+	}
+	return f
+}
+
+// findAt returns the offsets of the leftmost match starting at or after from, the start is -1 if there is none
+func (f *finder) findAt(text string, from int) (int, int) {
+	if f.nfa != nil {
+		return f.findAutomaton(text, from)
+	}
+	for start := from; start <= len(text); start = nextRune(text, start) {
+		if !strings.HasPrefix(text[start:], f.prefix) {
+			continue
+		}
+		if f.shortest {
+			for end := start + len(f.prefix); end <= len(text); end = nextRune(text, end) {
+				if f.m.Matches(text[start:end]) {
+					return start, end
+				}
+			}
+			continue
+		}
+		for end := len(text); end >= start+len(f.prefix); end = previousRune(text, end) {
+			if f.m.Matches(text[start:end]) {
+				return start, end
+			}
+		}
+	}
+	return -1, -1
+}
+
+// findAutomaton simulates the automaton from every start offset at once. The states of earlier starts
+// are added first, so in a state that is reached from several starts the earliest one wins
+func (f *finder) findAutomaton(text string, from int) (int, int) {
+	current, next := f.current, f.next
+	starts, nexts := f.starts, f.nexts
+	current.clear()
+	matchStart, matchEnd := -1, -1
+	for pos := from; ; {
+		if matchStart == -1 && strings.HasPrefix(text[pos:], f.prefix) {
+			f.add(current, starts, 0, pos)
+		}
+		for _, pc := range current.dense {
+			if f.nfa.insts[pc].op != nfaMatch {
+				continue
+			}
+			if start := starts[pc]; matchStart == -1 || start < matchStart || (start == matchStart && !f.shortest) {
+				matchStart, matchEnd = start, pos
+			}
+		}
+		if pos == len(text) || (matchStart != -1 && len(current.dense) == 0) {
+			return matchStart, matchEnd
+		}
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		next.clear()
+		for _, pc := range current.dense {
+			inst := f.nfa.insts[pc]
+			start := starts[pc]
+			// later starts can not win against a match anymore, and for the shortest match neither can its own start
+			if matchStart != -1 && (start > matchStart || (f.shortest && start == matchStart)) {
+				continue
+			}
+			if (inst.op == nfaRune && inst.r == r) || inst.op == nfaAny {
+				f.add(next, nexts, pc+1, start)
+			}
+		}
+		current, next = next, current
+		starts, nexts = nexts, starts
+		pos += size
+	}
+}
+
+// add works like the add of the nfa but remembers the start offset of every added state
+func (f *finder) add(s *stateSet, starts []int, pc int, start int) {
+	if s.contains(pc) {
+		return
+	}
+	s.insert(pc)
+	starts[pc] = start
+	inst := f.nfa.insts[pc]
+	switch inst.op {
+	case nfaJump:
+		f.add(s, starts, inst.x, start)
+	case nfaSplit:
+		f.add(s, starts, inst.x, start)
+		f.add(s, starts, inst.y, start)
+	}
+}
+
+// nextRune returns the offset of the rune after the one at i, or an offset after the text if i is its end
+func nextRune(text string, i int) int {
+	if i >= len(text) {
+		return i + 1
+	}
+	_, size := utf8.DecodeRuneInString(text[i:])
+	return i + size
+}
+
+// previousRune returns the offset of the rune before i, or -1 if i is the start of the text
+func previousRune(text string, i int) int {
+	if i == 0 {
+		return -1
+	}
+	_, size := utf8.DecodeLastRuneInString(text[:i])
+	return i - size
+}
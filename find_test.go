@@ -0,0 +1,94 @@
+package match_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindIndex(t *testing.T) {
+	m, err := match.Compile("error=[ 4?? | 5?? ]")
+	assert.NoError(t, err)
+	text := "status ok, error=503 and error=404, error=200"
+	assert.Equal(t, []int{11, 20}, match.FindIndex(m, text))
+	assert.Equal(t, [][]int{{11, 20}, {25, 34}}, match.FindAllIndex(m, text, -1))
+	assert.Equal(t, []string{"error=503"}, match.FindAllString(m, text, 1))
+	assert.Nil(t, match.FindIndex(m, "all good"))
+}
+
+func TestFindLongestAndShortest(t *testing.T) {
+	m, err := match.Compile("<*>")
+	assert.NoError(t, err)
+	text := "a <b> c <d> e"
+	assert.Equal(t, []string{"<b> c <d>"}, match.FindAllString(m, text, -1))
+	assert.Equal(t, []string{"<b>", "<d>"}, match.FindAllString(match.Shortest(m), text, -1))
+	assert.Equal(t, []int{2, 5}, match.FindIndex(match.Shortest(m), text))
+}
+
+func TestFindEmptyMatches(t *testing.T) {
+	m, err := match.Compile("[ x ]*")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 0}, {1, 3}, {4, 4}, {6, 6}}, match.FindAllIndex(m, "axxbä", -1))
+
+	m, err = match.Compile("ä?")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"äö", "äü"}, match.FindAllString(m, "äöäü", -1))
+}
+
+func TestSplit(t *testing.T) {
+	m, err := match.Compile("[ , | ; ]+")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", ""}, match.Split(m, "a,b;;c,", -1))
+	assert.Equal(t, []string{"a", "b;;c,"}, match.Split(m, "a,b;;c,", 2))
+	assert.Nil(t, match.Split(m, "a,b", 0))
+	assert.Equal(t, []string{""}, match.Split(m, "", -1))
+
+	m, err = match.Compile(`" "[ :int ]"ms "`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, match.Split(m, "a 12ms b 3ms c", -1))
+
+	m, err = match.Compile("[ x ]*")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, match.Split(m, "axxbc", -1))
+}
+
+// opaque hides the compiled matcher, so the search can not use its automaton
+type opaque struct {
+	m match.Matcher
+}
+
+func (o opaque) Matches(data string) bool {
+	return o.m.Matches(data)
+}
+
+func TestFindWithAndWithoutAutomaton(t *testing.T) {
+	texts := []string{"", "a", "axxbä", "a <b> c <d> e", "xxyxy", "ab.ab.abc", "error=503 error=40", "äöäü"}
+	for _, pattern := range []string{"<*>", "[ x ]*", "[ x ]+y", "ab.*", "*", "?", "[ ab. | a ]{1,2}", "error=[ 4?? | 5?? ]", "b | xy", "ä?"} {
+		m, err := match.Compile(pattern)
+		assert.NoError(t, err)
+		for _, text := range texts {
+			assert.Equal(t, match.FindAllIndex(opaque{m}, text, -1), match.FindAllIndex(m, text, -1), pattern+" in "+text)
+			assert.Equal(t, match.FindAllIndex(match.Shortest(opaque{m}), text, -1), match.FindAllIndex(match.Shortest(m), text, -1), pattern+" in "+text)
+		}
+	}
+}
+
+func BenchmarkFindIndex(b *testing.B) {
+	text := strings.Repeat("x", 5000)
+	m, _ := match.Compile("[ x ]+y")
+	b.Run("automaton", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			match.FindIndex(m, text)
+		}
+	})
+	// matchers that can not be compiled into an automaton try every start and end offset
+	text = text[:200]
+	m, _ = match.Compile("[ /re:x+/ ]y")
+	b.Run("matcher", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			match.FindIndex(m, text)
+		}
+	})
+}
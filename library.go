@@ -0,0 +1,198 @@
+package match
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Library holds named sub patterns that can be referenced as @name outside of groups.
+// References get expanded before the pattern is parsed, so the compiled matcher is the
+// same as if the sub pattern was written inline. Alternatives of a definition only apply to the reference.
+// Definitions can be added while patterns get compiled with the library
+type Library struct {
+	mu          sync.RWMutex
+	definitions map[string]string
+}
+
+// NewLibrary returns an empty library
+func NewLibrary() *Library {
+	return &Library{
+		definitions: map[string]string{},
+	}
+}
+
+// WithLibrary expands the references to the definitions of the library before the pattern gets compiled
+func WithLibrary(l *Library) Option {
+	return func(c *config) {
+		c.library = l
+	}
+}
+
+// Define adds a sub pattern under the given name, an existing definition with the same name gets replaced
+func (l *Library) Define(name string, pattern string) error {
+	if !isIdentifier(name) {
+		return errors.New("invalid definition name " + name)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.definitions[name] = pattern
+	return nil
+}
+
+func (l *Library) lookup(name string) (string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	definition, ok := l.definitions[name]
+	return definition, ok
+}
+
+// Parse adds all definitions of the given source, every line has the form
+//
+// @regions = [ eu-west-1 | eu-central-1 ]
+//
+// empty lines and lines starting with # get ignored
+func (l *Library) Parse(source string) error {
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pattern, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !strings.HasPrefix(name, "@") {
+			return errors.New("invalid definition: expected '@name = pattern' but got " + line)
+		}
+		if err := l.Define(name[1:], strings.TrimSpace(pattern)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expand replaces every reference outside of groups with its definition.
+// A definition with alternatives multiplies the alternative of the pattern it is referenced in,
+// so svc.@env.api with @env = prod | staging becomes svc.prod.api|svc.staging.api.
+// The offsets contain the position in the given pattern of every byte of the expanded one,
+// the bytes of a definition are positioned at the reference that led to them, and so are its errors
+func (l *Library) expand(pattern string) (string, []int, error) {
+	alternatives, positions, err := l.expandReferences(pattern, nil, -1)
+	if err != nil {
+		return "", nil, err
+	}
+	offsets := []int{}
+	for i := range alternatives {
+		if i > 0 {
+			// the separator gets the position of the end of the previous alternative
+			offsets = append(offsets, offsets[len(offsets)-1])
+		}
+		offsets = append(offsets, positions[i]...)
+	}
+	return strings.Join(alternatives, "|"), offsets, nil
+}
+
+// expandReferences expands the pattern into its alternatives together with the positions of their bytes,
+// stack contains the names of the definitions that are currently expanded. If pos is not -1
+// all bytes and errors get positioned there, because the pattern is itself a definition
+func (l *Library) expandReferences(pattern string, stack []string, pos int) ([]string, [][]int, error) {
+	alternatives, positions := []string{}, [][]int{}
+	current, currentPositions := []string{""}, [][]int{{}}
+	literal, literalPositions := strings.Builder{}, []int{}
+	// take adds the bytes of the pattern between from and to to the literal text
+	take := func(from, to int) {
+		literal.WriteString(pattern[from:to])
+		for i := from; i < to; i++ {
+			if pos != -1 {
+				literalPositions = append(literalPositions, pos)
+			} else {
+				literalPositions = append(literalPositions, i)
+			}
+		}
+	}
+	// flush appends the literal text since the last reference to every sequence of the current alternative
+	flush := func() {
+		for j := range current {
+			current[j] += literal.String()
+			currentPositions[j] = slices.Concat(currentPositions[j], literalPositions)
+		}
+		literal.Reset()
+		literalPositions = literalPositions[:0]
+	}
+	group := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			// escaped runes are never references
+			take(i, i+2)
+			i++
+			continue
+		case group && strings.HasPrefix(pattern[i:], regexpOpen):
+			if end := regexpEnd(pattern, i); end != -1 {
+				take(i, end)
+				i = end - 1
+				continue
+			}
+		case c == '"':
+			// quoted literals never contain references
+			if end := quoteEnd(pattern, i); end != -1 {
+				take(i, end)
+				i = end - 1
+				continue
+			}
+		case c == '[':
+			group = true
+		case c == ']':
+			group = false
+		case c == '|' && !group:
+			flush()
+			alternatives, positions = append(alternatives, current...), append(positions, currentPositions...)
+			current, currentPositions = []string{""}, [][]int{{}}
+			continue
+		case c == '@' && !group:
+			end := i + 1
+			for end < len(pattern) && isIdentifier(pattern[i+1:end+1]) {
+				end++
+			}
+			name := pattern[i+1 : end]
+			errPos := pos
+			if errPos == -1 {
+				errPos = i
+			}
+			definition, ok := l.lookup(name)
+			if !ok {
+				return nil, nil, &PatternError{Pos: errPos, Msg: "unknown definition @" + name}
+			}
+			for j, n := range stack {
+				if n == name {
+					return nil, nil, &PatternError{Pos: errPos, Msg: "cyclic definition @" + strings.Join(append(stack[j:], name), " -> @")}
+				}
+			}
+			sub, subPositions, err := l.expandReferences(definition, append(stack, name), errPos)
+			if err != nil {
+				return nil, nil, err
+			}
+			flush()
+			product, productPositions := []string{}, [][]int{}
+			for j, prefix := range current {
+				for k, alternative := range sub {
+					alternativePositions := subPositions[k]
+					if len(sub) > 1 {
+						trimmed := trimSpace(alternative)
+						start := strings.Index(alternative, trimmed)
+						alternative, alternativePositions = trimmed, alternativePositions[start:start+len(trimmed)]
+					}
+					product = append(product, prefix+alternative)
+					productPositions = append(productPositions, slices.Concat(currentPositions[j], alternativePositions))
+				}
+			}
+			current, currentPositions = product, productPositions
+			i = end - 1
+			continue
+		}
+		take(i, i+1)
+	}
+	flush()
+	return append(alternatives, current...), append(positions, currentPositions...), nil
+}
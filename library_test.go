@@ -0,0 +1,102 @@
+package match_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLibrary(t *testing.T) {
+	lib := match.NewLibrary()
+	assert.NoError(t, lib.Parse(`
+		# regions we deploy to
+		@regions = [ eu-west-1 | eu-central-1 | us-east-1 ]
+		@eu = [ eu-west-1 | eu-central-1 ]
+		@services = [ api | web ].@regions
+	`))
+
+	m, err := match.Compile("svc.@regions.*", match.WithLibrary(lib))
+	assert.NoError(t, err)
+	inline, err := match.Compile("svc.[ eu-west-1 | eu-central-1 | us-east-1 ].*")
+	assert.NoError(t, err)
+	for _, data := range []string{"svc.eu-west-1.logs", "svc.us-east-1.", "svc.ap-south-1.logs", "svc.@regions.logs"} {
+		assert.Equal(t, inline.Matches(data), m.Matches(data), data)
+	}
+
+	m, err = match.Compile("@services.[ logs | metrics ]", match.WithLibrary(lib))
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("web.eu-central-1.metrics"))
+	assert.Equal(t, false, m.Matches("db.eu-central-1.metrics"))
+
+	// alternatives of a definition stay within the reference
+	assert.NoError(t, lib.Define("env", "prod | staging"))
+	m, err = match.Compile("svc.@env.api | health", match.WithLibrary(lib))
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("svc.prod.api"))
+	assert.Equal(t, true, m.Matches("svc.staging.api"))
+	assert.Equal(t, true, m.Matches("health"))
+	assert.Equal(t, false, m.Matches("svc.prod"))
+	assert.Equal(t, false, m.Matches("staging.api"))
+
+	// inside of groups @name still references a predicate
+	match.RegisterPredicate("eu", func(data string) bool { return data == "predicate" })
+	m, err = match.Compile("svc.[@eu]", match.WithLibrary(lib))
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("svc.predicate"))
+	assert.Equal(t, false, m.Matches("svc.eu-west-1"))
+
+	// without a library @ is a literal
+	m, err = match.Compile("user@example.*")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("user@example.com"))
+}
+
+func TestLibraryErrors(t *testing.T) {
+	lib := match.NewLibrary()
+	assert.NoError(t, lib.Define("a", "x.@b"))
+	assert.NoError(t, lib.Define("b", "y.@c"))
+	assert.NoError(t, lib.Define("c", "@a"))
+	assert.Error(t, lib.Define("not valid", "x"))
+	assert.Error(t, lib.Parse("regions = [ a | b ]"))
+
+	_, err := match.Compile("svc.@a.*", match.WithLibrary(lib))
+	patternErr, ok := err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 4, patternErr.Pos)
+	assert.Contains(t, patternErr.Msg, "@a -> @b -> @c -> @a")
+
+	_, err = match.Compile("svc.@missing.*", match.WithLibrary(lib))
+	patternErr, ok = err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 4, patternErr.Pos)
+
+	_, err = match.CompileExpr("a.* || svc.@missing", match.WithLibrary(lib))
+	exprErr, ok := err.(*match.ExprError)
+	assert.True(t, ok)
+	assert.Equal(t, 11, exprErr.Pos)
+}
+
+func TestLibraryConcurrentDefine(t *testing.T) {
+	lib := match.NewLibrary()
+	assert.NoError(t, lib.Define("env", "prod"))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, lib.Parse("@region = eu | us"))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			m, err := match.Compile("svc.@env.*", match.WithLibrary(lib))
+			assert.NoError(t, err)
+			assert.True(t, m.Matches("svc.prod.api"))
+		}
+	}()
+	wg.Wait()
+}
@@ -1,5 +1,7 @@
 package match
 
+import "strings"
+
 type Matcher interface {
 	Matches(data string) bool
 }
@@ -15,23 +17,89 @@ type matcher struct {
 // djakwndaw[ m* | t* ]tewaljdm[ test | zetto ]rest
 //
 // ...
-func Compile(pattern string) (Matcher, error) {
-	parts, err := parseQueryIntoParts(pattern)
-	if err != nil {
-		return matcher{}, err
+//
+// A | outside of brackets separates alternative patterns, a.* | b.* matches everything either of them matches.
+//
+// Inside a pattern ? matches exactly one rune, * zero or more runes and + one or more runes.
+// The behaviour can be changed with options like WithOptionalQuestionMark.
+// A pattern starting with (?x) is written in the extended syntax, see WithExtendedSyntax
+func Compile(pattern string, opts ...Option) (Matcher, error) {
+	cfg := newConfig(opts)
+	start := 0
+	if strings.HasPrefix(pattern, extendedFlag) {
+		cfg.extended = true
+		start = len(extendedFlag)
 	}
-	parts = parsePatterns(parts)
-
-	prefix, patterns, suffix := extractPreAndSuffixFromParts(parts)
-	cartesianProduct := generateCartesianProduct(patterns)
-	preparedMatcher := combineFixData(prefix, suffix, cartesianProduct)
-	preparedMatcher = orderPreparedByComplexity(preparedMatcher)
+	if !cfg.extended {
+		return compileMatcher(pattern, cfg)
+	}
+	stripped, offsets := stripExtended(pattern[start:])
+	m, err := compileMatcher(stripped, cfg)
+	if patternErr, ok := err.(*PatternError); ok {
+		// the position has to point into the pattern as it was written, not into the stripped one
+		for i := range offsets {
+			offsets[i] += start
+		}
+		patternErr.relocate(offsets, len(pattern))
+	}
+	return m, err
+}
 
+// compileMatcher compiles a pattern that is not written in the extended syntax
+func compileMatcher(pattern string, cfg config) (Matcher, error) {
+	// offsets contain the position in the pattern as it was written of every byte of the expanded pattern
+	var offsets []int
+	length := len(pattern)
+	if cfg.library != nil {
+		expanded, positions, err := cfg.library.expand(pattern)
+		if err != nil {
+			return matcher{}, err
+		}
+		pattern, offsets = expanded, positions
+	}
+	prepared := []prepared{}
+	start := 0
+	for _, alternative := range splitOutsideGroups(pattern, "|") {
+		trimmed := cfg.trim(alternative)
+		p, err := compilePrepared(trimmed, cfg)
+		if patternErr, ok := err.(*PatternError); ok {
+			patternErr.Pos += start + strings.Index(alternative, trimmed)
+			if offsets != nil {
+				patternErr.relocate(offsets, length)
+			}
+			return matcher{}, patternErr
+		}
+		if err != nil {
+			return matcher{}, err
+		}
+		if cfg.unanchored {
+			for i := range p {
+				p[i] = unanchorPrepared(p[i])
+			}
+		}
+		prepared = append(prepared, p...)
+		start += len(alternative) + 1
+	}
 	return matcher{
-		prepared: preparedMatcher,
+		prepared: orderPreparedByComplexity(prepared),
 	}, nil
 }
 
+// compilePrepared compiles a pattern without top level alternatives into its prepared matchers
+func compilePrepared(pattern string, cfg config) ([]prepared, error) {
+	parts, err := parseQueryIntoParts(pattern, cfg)
+	if err != nil {
+		return nil, err
+	}
+	parts, err = parsePatterns(parts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	prefix, patterns, suffix := extractPreAndSuffixFromParts(parts)
+	cartesianProduct := generateCartesianProduct(patterns)
+	return combineFixData(prefix, suffix, patterns, cartesianProduct, cfg), nil
+}
+
 func (m matcher) Matches(data string) bool {
 	return matchMulti(m.prepared, data)
 }
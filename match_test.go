@@ -48,3 +48,180 @@ func BenchmarkMatch(b *testing.B) {
 		}
 	}
 }
+
+func TestMatchExclusions(t *testing.T) {
+	m, err := match.Compile("logs.[ * ! debug | trace ].out")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("logs.info.out"))
+	assert.Equal(t, true, m.Matches("logs.debugging.out"))
+	assert.Equal(t, false, m.Matches("logs.debug.out"))
+	assert.Equal(t, false, m.Matches("logs.trace.out"))
+
+	m, err = match.Compile("[ *.go ! *_test.go ]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("match.go"))
+	assert.Equal(t, false, m.Matches("match_test.go"))
+	assert.Equal(t, false, m.Matches("README.md"))
+
+	m, err = match.Compile("[ a | b ].[ * ! x ]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("a.y"))
+	assert.Equal(t, true, m.Matches("b.y"))
+	assert.Equal(t, false, m.Matches("a.x"))
+	assert.Equal(t, false, m.Matches("b.x"))
+}
+
+func TestMatchRepetitions(t *testing.T) {
+	m, err := match.Compile("a.[ seg. ]+leaf")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("a.seg.leaf"))
+	assert.Equal(t, true, m.Matches("a.seg.seg.seg.leaf"))
+	assert.Equal(t, false, m.Matches("a.leaf"))
+	assert.Equal(t, false, m.Matches("a.seg.other.leaf"))
+
+	m, err = match.Compile("[ a | b ]+")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("abba"))
+	assert.Equal(t, false, m.Matches("abc"))
+	assert.Equal(t, false, m.Matches(""))
+
+	m, err = match.Compile("a[ .* ]*")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("a"))
+	assert.Equal(t, true, m.Matches("a.b.c.d"))
+	assert.Equal(t, false, m.Matches("ab"))
+
+	m, err = match.Compile("[ seg. ]{1,3}end")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("seg.end"))
+	assert.Equal(t, true, m.Matches("seg.seg.seg.end"))
+	assert.Equal(t, false, m.Matches("end"))
+	assert.Equal(t, false, m.Matches("seg.seg.seg.seg.end"))
+
+	m, err = match.Compile("v[ 1 ]?.api")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("v.api"))
+	assert.Equal(t, true, m.Matches("v1.api"))
+	assert.Equal(t, false, m.Matches("v11.api"))
+
+	m, err = match.Compile("[ * ]+.[ x ]+")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("abc.xx"))
+	assert.Equal(t, false, m.Matches("abc.xy"))
+
+	_, err = match.Compile("[ a ]{2,1}")
+	assert.Error(t, err)
+}
+
+func TestMatchRegexps(t *testing.T) {
+	m, err := match.Compile("objects.[ /re:[0-9a-f]{8}-[0-9a-f]{4}/ | latest ].json")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("objects.deadbeef-0123.json"))
+	assert.Equal(t, true, m.Matches("objects.latest.json"))
+	assert.Equal(t, false, m.Matches("objects.deadbeef-012.json"))
+	assert.Equal(t, false, m.Matches("objects.deadbeef-0123x.json"))
+	assert.Equal(t, false, m.Matches("other.deadbeef-0123.json"))
+
+	m, err = match.Compile("v[ /re:\\d+/ ].[ * ]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("v12.api"))
+	assert.Equal(t, false, m.Matches("vx.api"))
+
+	_, err = match.Compile("[ /re:(/ ]")
+	assert.Error(t, err)
+}
+
+func TestMatchRanges(t *testing.T) {
+	m, err := match.Compile("shard-[ 0..127 ]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("shard-0"))
+	assert.Equal(t, true, m.Matches("shard-127"))
+	assert.Equal(t, false, m.Matches("shard-128"))
+	assert.Equal(t, false, m.Matches("shard-x"))
+
+	m, err = match.Compile("v[ 2..14 ].api")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("v2.api"))
+	assert.Equal(t, true, m.Matches("v14.api"))
+	assert.Equal(t, false, m.Matches("v1.api"))
+	assert.Equal(t, false, m.Matches("v15.api"))
+
+	m, err = match.Compile("node-[ 000..255 | -5..-1 ].[ * ]")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("node-042.local"))
+	assert.Equal(t, true, m.Matches("node--3.local"))
+	assert.Equal(t, false, m.Matches("node-42.local"))
+
+	_, err = match.Compile("shard-[ 9..1 ]")
+	assert.Error(t, err)
+}
+
+func TestMatchTopLevelAlternatives(t *testing.T) {
+	m, err := match.Compile("logs.* | metrics.[ cpu | mem ] | \\|pipe")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("logs.app"))
+	assert.Equal(t, true, m.Matches("metrics.cpu"))
+	assert.Equal(t, true, m.Matches("|pipe"))
+	assert.Equal(t, false, m.Matches("metrics.disk"))
+	assert.Equal(t, false, m.Matches("traces.app"))
+
+	m, err = match.Compile("a || b")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches(""))
+	assert.Equal(t, true, m.Matches("b"))
+
+	m, err = match.Compile("")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches(""))
+	assert.Equal(t, false, m.Matches("a"))
+}
+
+func TestMatchUnanchored(t *testing.T) {
+	m, err := match.Compile("status=[ 4?? | 5?? ] | panic", match.WithUnanchored())
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("request failed with status=503 after 2s"))
+	assert.Equal(t, true, m.Matches("panic"))
+	assert.Equal(t, true, m.Matches("goroutine panic: nil map"))
+	assert.Equal(t, false, m.Matches("request failed with status=302"))
+	assert.Equal(t, false, m.Matches("status=5"))
+}
+
+func TestMatchSegmentMarkerRunes(t *testing.T) {
+	m, err := match.Compile("[ :int ].[ \U000F0009* ]")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("1.\U000F0009x"))
+	assert.Equal(t, false, m.Matches("1.x"))
+
+	m, err = match.CompileTemplate("[ :int ].${v}*", map[string]string{"v": "\U000F0005"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("12.\U000F0005"))
+	assert.Equal(t, false, m.Matches("12.x"))
+
+	m, err = match.Compile("\U000F0000[ :int ]\U000F0001")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("\U000F00007\U000F0001"))
+	assert.Equal(t, false, m.Matches("7"))
+
+	// markers in the data never get mistaken for segments
+	m, err = match.Compile("[ :int ].*")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("7.\U000F0000\U000F0009"))
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+	_, err = match.Unmarshal(data)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,296 @@
+package match
+
+import (
+	"errors"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotStreamable is returned if a matcher can not be compiled into an automaton that matches streamed data
+var ErrNotStreamable = errors.New("matcher is not streamable")
+
+type nfaOp uint8
+
+const (
+	// nfaRune consumes exactly the rune of the instruction
+	nfaRune nfaOp = iota
+	// nfaAny consumes any single rune
+	nfaAny
+	// nfaSplit continues at both x and y without consuming anything
+	nfaSplit
+	// nfaJump continues at x without consuming anything
+	nfaJump
+	// nfaMatch accepts the input if it is fully consumed
+	nfaMatch
+)
+
+type nfaInst struct {
+	op nfaOp
+	r  rune
+	x  int
+	y  int
+}
+
+// nfa is a thompson automaton used for the parts of a pattern that can not
+// be expanded into a cartesian product, like unbounded repetitions.
+// It is simulated by tracking the set of active states, so matching is linear in the input
+type nfa struct {
+	insts []nfaInst
+
+	// sets holds pairs of state sets that get reused between matches, so matching does not allocate
+	sets sync.Pool
+}
+
+type nfaCompiler struct {
+	insts            []nfaInst
+	optionalQuestion bool
+}
+
+// compileRepetitionNFA compiles an automaton that matches min or more repetitions of any of the alternatives,
+// the alternatives are wildcard patterns
+func compileRepetitionNFA(alternatives []string, min int, max int, optionalQuestion bool) *nfa {
+	c := nfaCompiler{optionalQuestion: optionalQuestion}
+	c.repetition(alternatives, min, max)
+	c.emit(nfaInst{op: nfaMatch})
+	return &nfa{insts: c.insts}
+}
+
+// compilePreparedNFA compiles an automaton that matches everything one of the prepared matches.
+// Exclusions and segments other than repetitions and captures can not be expressed by the automaton
+func compilePreparedNFA(ps []prepared) (*nfa, error) {
+	c := nfaCompiler{}
+	if len(ps) == 0 {
+		// a rune that does not exist never gets consumed, so nothing matches
+		c.emit(nfaInst{op: nfaRune, r: -1})
+	}
+	jumps := []int{}
+	for i, p := range ps {
+		if len(p.excludes) > 0 {
+			return nil, ErrNotStreamable
+		}
+		if i == len(ps)-1 {
+			if err := c.prepared(p); err != nil {
+				return nil, err
+			}
+			break
+		}
+		split := c.emit(nfaInst{op: nfaSplit})
+		c.insts[split].x = len(c.insts)
+		if err := c.prepared(p); err != nil {
+			return nil, err
+		}
+		jumps = append(jumps, c.emit(nfaInst{op: nfaJump}))
+		c.insts[split].y = len(c.insts)
+	}
+	for _, jump := range jumps {
+		c.insts[jump].x = len(c.insts)
+	}
+	c.emit(nfaInst{op: nfaMatch})
+	return &nfa{insts: c.insts}, nil
+}
+
+// prepared emits code that matches the prefix, the pattern and the suffix of the prepared
+func (c *nfaCompiler) prepared(p prepared) error {
+	c.optionalQuestion = p.optionalQuestion
+	c.literal(p.prefix)
+	pattern := p.pattern
+	for {
+		marker := indexSegmentMarker(pattern)
+		if marker == -1 || p.segments == nil {
+			c.wildcard(pattern)
+			break
+		}
+		c.wildcard(pattern[:marker])
+		r, size := utf8.DecodeRuneInString(pattern[marker:])
+		if segmentIndex(r) >= len(p.segments) {
+			return ErrNotStreamable
+		}
+		switch seg := p.segments[segmentIndex(r)].(type) {
+		case *captureSegment:
+		case *repeatSegment:
+			c.optionalQuestion = seg.optionalQuestion
+			c.repetition(seg.alternatives, seg.min, seg.max)
+			c.optionalQuestion = p.optionalQuestion
+		default:
+			return ErrNotStreamable
+		}
+		pattern = pattern[marker+size:]
+	}
+	c.literal(p.suffix)
+	return nil
+}
+
+// repetition emits code that matches min to max repetitions of any of the alternatives, a max of -1 means unbounded
+func (c *nfaCompiler) repetition(alternatives []string, min int, max int) {
+	for i := 0; i < min; i++ {
+		c.alternatives(alternatives)
+	}
+	if max != -1 {
+		// every further repetition is optional, skipping one skips all the following ones
+		skips := []int{}
+		for i := min; i < max; i++ {
+			skip := c.emit(nfaInst{op: nfaSplit})
+			c.insts[skip].x = len(c.insts)
+			skips = append(skips, skip)
+			c.alternatives(alternatives)
+		}
+		for _, skip := range skips {
+			c.insts[skip].y = len(c.insts)
+		}
+		return
+	}
+	loop := c.emit(nfaInst{op: nfaSplit})
+	c.insts[loop].x = len(c.insts)
+	c.alternatives(alternatives)
+	c.emit(nfaInst{op: nfaJump, x: loop})
+	c.insts[loop].y = len(c.insts)
+}
+
+// literal emits code that matches exactly the given text
+func (c *nfaCompiler) literal(text string) {
+	for _, r := range text {
+		c.emit(nfaInst{op: nfaRune, r: r})
+	}
+}
+
+func (c *nfaCompiler) emit(inst nfaInst) int {
+	c.insts = append(c.insts, inst)
+	return len(c.insts) - 1
+}
+
+// alternatives emits code that matches one of the alternatives and continues after it
+func (c *nfaCompiler) alternatives(alternatives []string) {
+	jumps := []int{}
+	for i, alternative := range alternatives {
+		if i == len(alternatives)-1 {
+			c.wildcard(alternative)
+			break
+		}
+		split := c.emit(nfaInst{op: nfaSplit})
+		c.insts[split].x = len(c.insts)
+		c.wildcard(alternative)
+		jumps = append(jumps, c.emit(nfaInst{op: nfaJump}))
+		c.insts[split].y = len(c.insts)
+	}
+	for _, jump := range jumps {
+		c.insts[jump].x = len(c.insts)
+	}
+}
+
+// wildcard emits code that matches the wildcard pattern
+func (c *nfaCompiler) wildcard(pattern string) {
+	escaped := false
+	for _, r := range pattern {
+		if escaped {
+			c.emit(nfaInst{op: nfaRune, r: r})
+			escaped = false
+			continue
+		}
+		switch r {
+		case '\\':
+			escaped = true
+		case '*', '+':
+			if r == '+' {
+				c.emit(nfaInst{op: nfaAny})
+			}
+			split := c.emit(nfaInst{op: nfaSplit})
+			c.insts[split].x = c.emit(nfaInst{op: nfaAny})
+			c.emit(nfaInst{op: nfaJump, x: split})
+			c.insts[split].y = len(c.insts)
+		case '?':
+			if c.optionalQuestion {
+				split := c.emit(nfaInst{op: nfaSplit})
+				c.insts[split].x = c.emit(nfaInst{op: nfaAny})
+				c.insts[split].y = len(c.insts)
+				continue
+			}
+			c.emit(nfaInst{op: nfaAny})
+		default:
+			c.emit(nfaInst{op: nfaRune, r: r})
+		}
+	}
+}
+
+// matches reports if the automaton accepts the whole data
+func (n *nfa) matches(data string) bool {
+	sets, ok := n.sets.Get().(*[2]*stateSet)
+	if !ok {
+		sets = &[2]*stateSet{newStateSet(len(n.insts)), newStateSet(len(n.insts))}
+	}
+	defer n.sets.Put(sets)
+	current, next := sets[0], sets[1]
+	current.clear()
+	n.add(current, 0)
+	for _, r := range data {
+		if len(current.dense) == 0 {
+			return false
+		}
+		n.step(current, next, r)
+		current, next = next, current
+	}
+	return n.accepts(current)
+}
+
+// step consumes the rune in every state of current and stores the resulting states in next
+func (n *nfa) step(current *stateSet, next *stateSet, r rune) {
+	next.clear()
+	for _, pc := range current.dense {
+		inst := n.insts[pc]
+		if (inst.op == nfaRune && inst.r == r) || inst.op == nfaAny {
+			n.add(next, pc+1)
+		}
+	}
+}
+
+// accepts reports if one of the states is the match state
+func (n *nfa) accepts(s *stateSet) bool {
+	for _, pc := range s.dense {
+		if n.insts[pc].op == nfaMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the state and all states reachable from it without consuming input
+func (n *nfa) add(s *stateSet, pc int) {
+	if s.contains(pc) {
+		return
+	}
+	s.insert(pc)
+	inst := n.insts[pc]
+	switch inst.op {
+	case nfaJump:
+		n.add(s, inst.x)
+	case nfaSplit:
+		n.add(s, inst.x)
+		n.add(s, inst.y)
+	}
+}
+
+// stateSet is a sparse set of instruction indexes that can be cleared in constant time
+type stateSet struct {
+	dense  []int
+	sparse []int
+}
+
+func newStateSet(size int) *stateSet {
+	return &stateSet{
+		dense:  make([]int, 0, size),
+		sparse: make([]int, size),
+	}
+}
+
+func (s *stateSet) contains(pc int) bool {
+	i := s.sparse[pc]
+	return i < len(s.dense) && s.dense[i] == pc
+}
+
+func (s *stateSet) insert(pc int) {
+	s.sparse[pc] = len(s.dense)
+	s.dense = append(s.dense, pc)
+}
+
+func (s *stateSet) clear() {
+	s.dense = s.dense[:0]
+}
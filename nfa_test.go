@@ -0,0 +1,35 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepetitionNFA(t *testing.T) {
+	testCases := []struct {
+		alternatives []string
+		min          int
+		text         string
+		matched      bool
+	}{
+		{alternatives: []string{"a", "b"}, min: 1, text: "a", matched: true},
+		{alternatives: []string{"a", "b"}, min: 1, text: "abba", matched: true},
+		{alternatives: []string{"a", "b"}, min: 1, text: "", matched: false},
+		{alternatives: []string{"a", "b"}, min: 1, text: "abc", matched: false},
+		{alternatives: []string{"a", "b"}, min: 0, text: "", matched: true},
+		{alternatives: []string{"a", "b"}, min: 3, text: "ab", matched: false},
+		{alternatives: []string{"a", "b"}, min: 3, text: "aba", matched: true},
+		{alternatives: []string{"seg."}, min: 1, text: "seg.seg.", matched: true},
+		{alternatives: []string{"seg."}, min: 1, text: "seg.seg", matched: false},
+		{alternatives: []string{".*"}, min: 0, text: ".a.bc.", matched: true},
+		{alternatives: []string{".*"}, min: 0, text: "a.bc", matched: false},
+		{alternatives: []string{"?x"}, min: 1, text: "axbx", matched: true},
+		{alternatives: []string{"?x"}, min: 1, text: "axb", matched: false},
+		{alternatives: []string{"*"}, min: 1, text: "", matched: true},
+	}
+	for _, testCase := range testCases {
+		n := compileRepetitionNFA(testCase.alternatives, testCase.min, -1, false)
+		assert.Equal(t, testCase.matched, n.matches(testCase.text), "%v{%d,} %s", testCase.alternatives, testCase.min, testCase.text)
+	}
+}
@@ -0,0 +1,67 @@
+package match
+
+// Option changes how a pattern gets compiled
+type Option func(*config)
+
+type config struct {
+	optionalQuestion bool
+	registry         *Registry
+	library          *Library
+	keepWhitespace   bool
+	extended         bool
+	unanchored       bool
+}
+
+// WithOptionalQuestionMark makes ? match zero or one rune instead of exactly one rune
+func WithOptionalQuestionMark() Option {
+	return func(c *config) {
+		c.optionalQuestion = true
+	}
+}
+
+// WithRegistry resolves the predicates of the pattern from the given registry instead of the default registry
+func WithRegistry(r *Registry) Option {
+	return func(c *config) {
+		c.registry = r
+	}
+}
+
+// WithoutTrimming keeps the whitespace around static parts and alternatives, by default it gets trimmed
+func WithoutTrimming() Option {
+	return func(c *config) {
+		c.keepWhitespace = true
+	}
+}
+
+// WithExtendedSyntax ignores all whitespace that is not quoted or escaped and allows comments
+// from # to the end of the line, so long patterns can be written over several lines.
+// The same can be achieved by starting the pattern with (?x)
+func WithExtendedSyntax() Option {
+	return func(c *config) {
+		c.extended = true
+	}
+}
+
+// WithUnanchored lets a pattern match anywhere inside of the data instead of the whole data,
+// as if every alternative of the pattern was surrounded by *
+func WithUnanchored() Option {
+	return func(c *config) {
+		c.unanchored = true
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{registry: defaultRegistry}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// trim removes the surrounding whitespace of a part or an alternative unless it should be kept
+func (c config) trim(s string) string {
+	if c.keepWhitespace {
+		return s
+	}
+	return trimSpace(s)
+}
@@ -0,0 +1,110 @@
+package match
+
+import (
+	"net/netip"
+	"strconv"
+	"unicode"
+)
+
+// placeholderType validates the segment assigned to a typed placeholder like [:int]
+// and returns its parsed value
+type placeholderType func(data string) (any, bool)
+
+// placeholderTypes contains the built in types that can be used as [:type] inside of a pattern
+var placeholderTypes = map[string]placeholderType{
+	// int matches a decimal number with an optional sign, its value is an int64
+	"int": func(data string) (any, bool) {
+		if data == "" || data[0] == '+' {
+			return nil, false
+		}
+		v, err := strconv.ParseInt(data, 10, 64)
+		return v, err == nil
+	},
+	// uuid matches a uuid in its canonical 8-4-4-4-12 form, its value is the string
+	"uuid": func(data string) (any, bool) {
+		if len(data) != 36 {
+			return nil, false
+		}
+		for i := 0; i < len(data); i++ {
+			if i == 8 || i == 13 || i == 18 || i == 23 {
+				if data[i] != '-' {
+					return nil, false
+				}
+			} else if !isHexDigit(data[i]) {
+				return nil, false
+			}
+		}
+		return data, true
+	},
+	// hex matches one or more hexadecimal digits, its value is the string
+	"hex": func(data string) (any, bool) {
+		if data == "" {
+			return nil, false
+		}
+		for i := 0; i < len(data); i++ {
+			if !isHexDigit(data[i]) {
+				return nil, false
+			}
+		}
+		return data, true
+	},
+	// alpha matches one or more letters, its value is the string
+	"alpha": func(data string) (any, bool) {
+		if data == "" {
+			return nil, false
+		}
+		for _, r := range data {
+			if !unicode.IsLetter(r) {
+				return nil, false
+			}
+		}
+		return data, true
+	},
+	// ipv4 matches an ipv4 address in dotted decimal form, its value is a netip.Addr
+	"ipv4": func(data string) (any, bool) {
+		addr, err := netip.ParseAddr(data)
+		if err != nil || !addr.Is4() {
+			return nil, false
+		}
+		return addr, true
+	},
+	// word matches one or more ascii letters, digits or underscores, its value is the string
+	"word": func(data string) (any, bool) {
+		if data == "" {
+			return nil, false
+		}
+		for i := 0; i < len(data); i++ {
+			c := data[i]
+			if !(c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+				return nil, false
+			}
+		}
+		return data, true
+	},
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// placeholderSegment matches data that is valid for its type
+type placeholderSegment struct {
+	name  string
+	parse placeholderType
+}
+
+func newPlaceholderSegment(name string) (*placeholderSegment, bool) {
+	parse, ok := placeholderTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return &placeholderSegment{
+		name:  name,
+		parse: parse,
+	}, true
+}
+
+func (s *placeholderSegment) matches(data string) bool {
+	_, ok := s.parse(data)
+	return ok
+}
@@ -0,0 +1,47 @@
+package match
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaceholderTypes(t *testing.T) {
+	testCases := []struct {
+		typ     string
+		data    string
+		matched bool
+		value   any
+	}{
+		{typ: "int", data: "42", matched: true, value: int64(42)},
+		{typ: "int", data: "-7", matched: true, value: int64(-7)},
+		{typ: "int", data: "+7", matched: false},
+		{typ: "int", data: "4x", matched: false},
+		{typ: "int", data: "", matched: false},
+		{typ: "uuid", data: "123e4567-e89b-12d3-a456-426614174000", matched: true, value: "123e4567-e89b-12d3-a456-426614174000"},
+		{typ: "uuid", data: "123e4567e89b12d3a456426614174000", matched: false},
+		{typ: "uuid", data: "123e4567-e89b-12d3-a456-42661417400g", matched: false},
+		{typ: "hex", data: "DEADbeef", matched: true, value: "DEADbeef"},
+		{typ: "hex", data: "xyz", matched: false},
+		{typ: "alpha", data: "Grüße", matched: true, value: "Grüße"},
+		{typ: "alpha", data: "abc1", matched: false},
+		{typ: "ipv4", data: "10.0.0.1", matched: true, value: netip.MustParseAddr("10.0.0.1")},
+		{typ: "ipv4", data: "10.0.0.256", matched: false},
+		{typ: "ipv4", data: "::1", matched: false},
+		{typ: "word", data: "user_42", matched: true, value: "user_42"},
+		{typ: "word", data: "user-42", matched: false},
+	}
+	for _, testCase := range testCases {
+		seg, ok := newPlaceholderSegment(testCase.typ)
+		assert.True(t, ok)
+		assert.Equal(t, testCase.matched, seg.matches(testCase.data), testCase.typ+" "+testCase.data)
+		if testCase.matched {
+			value, _ := seg.parse(testCase.data)
+			assert.Equal(t, testCase.value, value, testCase.typ+" "+testCase.data)
+		}
+	}
+
+	_, ok := newPlaceholderSegment("float")
+	assert.False(t, ok)
+}
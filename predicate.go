@@ -0,0 +1,85 @@
+package match
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Registry holds the predicates that can be referenced as [@name] inside of patterns,
+// the names get resolved when a pattern is compiled
+type Registry struct {
+	mu         sync.RWMutex
+	predicates map[string]func(data string) bool
+}
+
+// PatternError describes an invalid pattern and the byte position in the pattern where the error occurred
+type PatternError struct {
+	Pos int
+	Msg string
+}
+
+// predicateSegment matches data its predicate accepts
+type predicateSegment struct {
+	name      string
+	predicate func(data string) bool
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		predicates: map[string]func(data string) bool{},
+	}
+}
+
+// RegisterPredicate registers a predicate in the default registry,
+// patterns compiled without WithRegistry resolve their predicates from it
+func RegisterPredicate(name string, predicate func(data string) bool) {
+	defaultRegistry.RegisterPredicate(name, predicate)
+}
+
+// RegisterPredicate registers a predicate under the given name, an existing predicate with the same name gets replaced.
+// The predicate gets called with exactly the segment of the data the pattern assigned to it
+func (r *Registry) RegisterPredicate(name string, predicate func(data string) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.predicates[name] = predicate
+}
+
+func (r *Registry) lookup(name string) (func(data string) bool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	predicate, ok := r.predicates[name]
+	return predicate, ok
+}
+
+func (r *Registry) newPredicateSegment(name string) (*predicateSegment, error) {
+	predicate, ok := r.lookup(name)
+	if !ok {
+		return nil, &PatternError{Msg: "unknown predicate @" + name}
+	}
+	return &predicateSegment{
+		name:      name,
+		predicate: predicate,
+	}, nil
+}
+
+func (s *predicateSegment) matches(data string) bool {
+	return s.predicate(data)
+}
+
+func (e *PatternError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+// relocate maps the position of the error in a rewritten pattern back to the pattern it was rewritten from,
+// offsets contain the position of every byte of the rewritten pattern and length is the length of the original one
+func (e *PatternError) relocate(offsets []int, length int) {
+	switch {
+	case e.Pos >= 0 && e.Pos < len(offsets):
+		e.Pos = offsets[e.Pos]
+	case e.Pos == len(offsets):
+		e.Pos = length
+	}
+}
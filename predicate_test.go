@@ -0,0 +1,104 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPredicates(t *testing.T) {
+	segments := []string{}
+	registry := match.NewRegistry()
+	registry.RegisterPredicate("tenant", func(data string) bool {
+		segments = append(segments, data)
+		return data == "acme" || data == "globex"
+	})
+
+	m, err := match.Compile("tenants.[@tenant].[ * ]", match.WithRegistry(registry))
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, m.Matches("tenants.acme.users"))
+	assert.Equal(t, true, m.Matches("tenants.globex.users"))
+	assert.Equal(t, false, m.Matches("tenants.initech.users"))
+	assert.Equal(t, false, m.Matches("other.acme.users"))
+
+	// the predicate only sees the splits where the rest of the pattern matches
+	assert.Equal(t, []string{"acme", "globex", "initech"}, segments)
+
+	m, err = match.Compile("[@tenant]", match.WithRegistry(registry))
+	assert.NoError(t, err)
+	segments = segments[:0]
+	assert.Equal(t, true, m.Matches("acme"))
+	assert.Equal(t, []string{"acme"}, segments)
+
+	m, err = match.Compile("[@tenant]-[ * ]", match.WithRegistry(registry))
+	assert.NoError(t, err)
+	segments = segments[:0]
+	assert.Equal(t, false, m.Matches("x-acme-y"))
+	assert.Equal(t, []string{"x", "x-acme"}, segments)
+}
+
+func TestPredicateErrors(t *testing.T) {
+	_, err := match.Compile("tenants.[ admin | @missing ]", match.WithRegistry(match.NewRegistry()))
+	patternErr, ok := err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 18, patternErr.Pos)
+
+	_, err = match.CompileExpr("a.* && tenants.[@missing]", match.WithRegistry(match.NewRegistry()))
+	exprErr, ok := err.(*match.ExprError)
+	assert.True(t, ok)
+	assert.Equal(t, 16, exprErr.Pos)
+
+	// the position is the one of the reference that failed, not of the first similar text
+	registry := match.NewRegistry()
+	registry.RegisterPredicate("tenant", func(data string) bool { return true })
+	for pattern, pos := range map[string]int{
+		"a.[@tenant] | b.[@ten]":           17,
+		`[ "@ten x" | @tenant | @ten ]`:    23,
+		"[:id @ten ! @tenant]":             5,
+		"[ @tenant ! x | @ten ]":           16,
+		"[ \\| | @ten ]{2}":                7,
+		"x.[ @tenant ]-[ a | b ] | [@ten]": 27,
+	} {
+		_, err = match.Compile(pattern, match.WithRegistry(registry))
+		patternErr, ok = err.(*match.PatternError)
+		assert.True(t, ok, pattern)
+		assert.Equal(t, pos, patternErr.Pos, pattern)
+	}
+
+	// errors inside of definitions are positioned at their reference
+	lib := match.NewLibrary()
+	assert.NoError(t, lib.Define("r", "[ @nope ]"))
+	_, err = match.Compile("xxxxxxx.@r", match.WithLibrary(lib), match.WithRegistry(registry))
+	patternErr, ok = err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 8, patternErr.Pos)
+
+	assert.NoError(t, lib.Define("env", "prod | staging"))
+	_, err = match.Compile("@env.[@ten]", match.WithLibrary(lib), match.WithRegistry(registry))
+	patternErr, ok = err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 6, patternErr.Pos)
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	match.RegisterPredicate("even", func(data string) bool {
+		return len(data)%2 == 0
+	})
+
+	m, err := match.Compile("[@even].txt")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("ab.txt"))
+	assert.Equal(t, false, m.Matches("abc.txt"))
+
+	data, err := match.Marshal(m)
+	assert.NoError(t, err)
+
+	loaded, err := match.Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, true, loaded.Matches("ab.txt"))
+
+	_, err = match.Unmarshal(data, match.WithRegistry(match.NewRegistry()))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotedLiterals(t *testing.T) {
+	m, err := match.Compile(`log: [ "  padded " | "a | b" ]" [x]"`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("log:  padded  [x]"))
+	assert.Equal(t, true, m.Matches("log:a | b [x]"))
+	assert.Equal(t, false, m.Matches("log:padded [x]"))
+
+	m, err = match.Compile(`"say \"hi\""*`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches(`say "hi" there`))
+
+	_, err = match.Compile(`[ "open ]`)
+	assert.Error(t, err)
+
+	m, err = match.CompileExpr(`"a b" || "c && d"`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("a b"))
+	assert.Equal(t, true, m.Matches("c && d"))
+}
+
+func TestWithoutTrimming(t *testing.T) {
+	m, err := match.Compile("hello [world| there ]", match.WithoutTrimming())
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("hello world"))
+	assert.Equal(t, true, m.Matches("hello  there "))
+	assert.Equal(t, false, m.Matches("helloworld"))
+
+	m, err = match.Compile("hello [ world ]")
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("helloworld"))
+	assert.Equal(t, false, m.Matches("hello world"))
+}
@@ -0,0 +1,72 @@
+package match
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Replace matches the whole data and returns the template with the captured groups filled in,
+// the bool reports if the data matched.
+//
+// # The template refers to the groups by name or by position:
+//
+// ${id} is the group named id, ${1} the first group of the pattern and ${0} the whole data.
+// References to groups that do not exist are replaced with an empty string
+func Replace(m Matcher, data string, template string) (string, bool) {
+	groups, ok := Capture(m, data)
+	if !ok {
+		return data, false
+	}
+	return expandTemplate(template, data, groups), true
+}
+
+// ReplaceAll replaces every match of m inside the text with the expanded template,
+// the matches are found like in FindAllIndex and the template works like in Replace
+func ReplaceAll(m Matcher, text string, template string) string {
+	replaced := strings.Builder{}
+	last := 0
+	for _, match := range FindAllIndex(m, text, -1) {
+		data := text[match[0]:match[1]]
+		groups, _ := Capture(m, data)
+		replaced.WriteString(text[last:match[0]])
+		replaced.WriteString(expandTemplate(template, data, groups))
+		last = match[1]
+	}
+	replaced.WriteString(text[last:])
+	return replaced.String()
+}
+
+// expandTemplate replaces the group references of the template
+func expandTemplate(template string, data string, groups Groups) string {
+	expanded := strings.Builder{}
+	for {
+		start := strings.Index(template, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end == -1 {
+			break
+		}
+		expanded.WriteString(template[:start])
+		expanded.WriteString(groupText(template[start+2:start+end], data, groups))
+		template = template[start+end+1:]
+	}
+	expanded.WriteString(template)
+	return expanded.String()
+}
+
+// groupText returns the text of the group with the given name or position
+func groupText(ref string, data string, groups Groups) string {
+	if i, err := strconv.Atoi(ref); err == nil {
+		switch {
+		case i == 0:
+			return data
+		case i > 0 && i <= len(groups):
+			return groups[i-1].Text
+		}
+		return ""
+	}
+	group, _ := groups.Get(ref)
+	return group.Text
+}
@@ -0,0 +1,32 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplace(t *testing.T) {
+	m, err := match.Compile("users.[:id *].[:section profile | settings ]")
+	assert.NoError(t, err)
+
+	replaced, ok := match.Replace(m, "users.42.profile", "${section}/${id}")
+	assert.True(t, ok)
+	assert.Equal(t, "profile/42", replaced)
+
+	replaced, ok = match.Replace(m, "users.42.settings", "${2}/${1} (${0}) ${missing}${3}")
+	assert.True(t, ok)
+	assert.Equal(t, "settings/42 (users.42.settings) ", replaced)
+
+	replaced, ok = match.Replace(m, "groups.42.profile", "${id}")
+	assert.False(t, ok)
+	assert.Equal(t, "groups.42.profile", replaced)
+}
+
+func TestReplaceAll(t *testing.T) {
+	m, err := match.Compile("[:key :word]=[:value :int]")
+	assert.NoError(t, err)
+	assert.Equal(t, "took ms:120, retries:3 ok", match.ReplaceAll(m, "took ms=120, retries=3 ok", "${key}:${value}"))
+	assert.Equal(t, "nothing", match.ReplaceAll(m, "nothing", "${key}"))
+}
@@ -0,0 +1,198 @@
+package match
+
+import (
+	"bufio"
+	"io"
+)
+
+// ScanOption changes how a Scanner selects its lines
+type ScanOption func(*scanConfig)
+
+type scanConfig struct {
+	invert   bool
+	before   int
+	after    int
+	maxCount int
+	split    bufio.SplitFunc
+	buf      []byte
+	maxSize  int
+}
+
+// ScanInverted selects the lines that are not matched instead of the matched ones
+func ScanInverted() ScanOption {
+	return func(c *scanConfig) {
+		c.invert = true
+	}
+}
+
+// ScanContext adds up to before lines in front of every selected line and up to after lines behind it
+func ScanContext(before int, after int) ScanOption {
+	return func(c *scanConfig) {
+		c.before = before
+		c.after = after
+	}
+}
+
+// ScanMaxCount stops the scanner after n selected lines and the context lines behind the last one
+func ScanMaxCount(n int) ScanOption {
+	return func(c *scanConfig) {
+		c.maxCount = n
+	}
+}
+
+// ScanSplit splits the data with the given function instead of bufio.ScanLines
+func ScanSplit(split bufio.SplitFunc) ScanOption {
+	return func(c *scanConfig) {
+		c.split = split
+	}
+}
+
+// ScanBuffer sets the initial buffer and the maximum size of a line, see bufio.Scanner.Buffer
+func ScanBuffer(buf []byte, max int) ScanOption {
+	return func(c *scanConfig) {
+		c.buf = buf
+		c.maxSize = max
+	}
+}
+
+// Line is a line returned by a Scanner
+type Line struct {
+	// Number is the number of the line starting at 1
+	Number int
+	// Offset is the byte offset of the line inside of the read data
+	Offset int64
+	// Bytes holds the line without its line ending, it is only valid until the next call of Scan
+	Bytes []byte
+	// Selected is false for context lines
+	Selected bool
+}
+
+// Text returns a copy of the line as a string
+func (l Line) Text() string {
+	return string(l.Bytes)
+}
+
+// Scanner reads lines from a reader and returns the ones selected by a matcher together with their context,
+// it works like grep. The buffers get reused, so scanning with compiled matchers without predicates
+// and their combinations does not allocate per line
+type Scanner struct {
+	m Matcher
+	// view is set if the lines can be matched without copying them, see MatchesBytes
+	view bool
+	sc   *bufio.Scanner
+	cfg  scanConfig
+
+	// consumed is the amount of bytes the split function advanced over, tokenOffset the offset of the last token
+	consumed    int64
+	tokenOffset int64
+	number      int
+	selected    int
+
+	// before holds the last lines that were not returned, their buffers get reused
+	before  []Line
+	nbefore int
+	// queue holds the lines that get returned before the next line is read
+	queue  []Line
+	nqueue int
+	// after is the amount of context lines that still have to be returned
+	after int
+
+	line Line
+}
+
+// NewScanner returns a scanner that selects the lines of r that are matched by m
+func NewScanner(r io.Reader, m Matcher, opts ...ScanOption) *Scanner {
+	cfg := scanConfig{split: bufio.ScanLines}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	s := &Scanner{
+		m:      m,
+		view:   canView(m),
+		sc:     bufio.NewScanner(r),
+		cfg:    cfg,
+		before: make([]Line, cfg.before),
+	}
+	s.sc.Split(s.split)
+	if cfg.buf != nil || cfg.maxSize > 0 {
+		s.sc.Buffer(cfg.buf, cfg.maxSize)
+	}
+	return s
+}
+
+// split tracks the offsets of the tokens of the split function
+func (s *Scanner) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := s.cfg.split(data, atEOF)
+	if token != nil {
+		s.tokenOffset = s.consumed
+		// split functions like bufio.ScanWords skip data in front of the token
+		if skipped := cap(data) - cap(token); len(token) > 0 && skipped >= 0 && skipped < len(data) && &data[skipped] == &token[0] {
+			s.tokenOffset += int64(skipped)
+		}
+	}
+	s.consumed += int64(advance)
+	return advance, token, err
+}
+
+// Scan advances to the next selected or context line, it returns false at the end of the data,
+// after an error or once the maximum count of selected lines and their context was returned
+func (s *Scanner) Scan() bool {
+	for {
+		if s.nqueue < len(s.queue) {
+			s.line = s.queue[s.nqueue]
+			s.nqueue++
+			return true
+		}
+		if s.cfg.maxCount > 0 && s.selected >= s.cfg.maxCount && s.after == 0 {
+			return false
+		}
+		if !s.sc.Scan() {
+			return false
+		}
+		s.number++
+		line := Line{Number: s.number, Offset: s.tokenOffset, Bytes: s.sc.Bytes()}
+		if matchesBytes(s.m, line.Bytes, s.view) != s.cfg.invert && (s.cfg.maxCount <= 0 || s.selected < s.cfg.maxCount) {
+			s.selected++
+			line.Selected = true
+			s.queue = append(append(s.queue[:0], s.before[:s.nbefore]...), line)
+			s.nqueue = 0
+			s.nbefore = 0
+			s.after = s.cfg.after
+			continue
+		}
+		if s.after > 0 {
+			s.after--
+			s.line = line
+			return true
+		}
+		s.remember(line)
+	}
+}
+
+// remember keeps a copy of the line as context in front of the next selected line
+func (s *Scanner) remember(line Line) {
+	if len(s.before) == 0 {
+		return
+	}
+	if s.nbefore == len(s.before) {
+		oldest := s.before[0]
+		copy(s.before, s.before[1:])
+		s.before[len(s.before)-1] = oldest
+		s.nbefore--
+	}
+	slot := &s.before[s.nbefore]
+	slot.Number = line.Number
+	slot.Offset = line.Offset
+	slot.Bytes = append(slot.Bytes[:0], line.Bytes...)
+	s.nbefore++
+}
+
+// Line returns the line of the last successful call of Scan
+func (s *Scanner) Line() Line {
+	return s.line
+}
+
+// Err returns the first error that occurred while reading
+func (s *Scanner) Err() error {
+	return s.sc.Err()
+}
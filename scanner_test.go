@@ -0,0 +1,77 @@
+package match_test
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func scanAll(t *testing.T, text string, pattern string, opts ...match.ScanOption) []string {
+	m, err := match.Compile(pattern)
+	assert.NoError(t, err)
+	s := match.NewScanner(strings.NewReader(text), m, opts...)
+	lines := []string{}
+	for s.Scan() {
+		line := s.Line()
+		marker := "-"
+		if line.Selected {
+			marker = ":"
+		}
+		assert.True(t, strings.HasPrefix(text[line.Offset:], line.Text()))
+		lines = append(lines, fmt.Sprintf("%d%s%s", line.Number, marker, line.Text()))
+	}
+	assert.NoError(t, s.Err())
+	return lines
+}
+
+func TestScanner(t *testing.T) {
+	text := "info start\nerror disk\ninfo a\ninfo b\r\ninfo c\nerror net\ninfo d\ninfo e"
+
+	assert.Equal(t, []string{"2:error disk", "6:error net"}, scanAll(t, text, "error *"))
+	assert.Equal(t, []string{"1:info start", "3:info a", "4:info b", "5:info c", "7:info d", "8:info e"}, scanAll(t, text, "error *", match.ScanInverted()))
+	assert.Equal(t, []string{"2:error disk"}, scanAll(t, text, "error *", match.ScanMaxCount(1)))
+
+	assert.Equal(t, []string{
+		"1-info start", "2:error disk", "3-info a",
+		"5-info c", "6:error net", "7-info d",
+	}, scanAll(t, text, "error *", match.ScanContext(1, 1)))
+
+	// overlapping context gets returned only once
+	assert.Equal(t, []string{
+		"1-info start", "2:error disk", "3-info a", "4-info b", "5-info c", "6:error net", "7-info d", "8-info e",
+	}, scanAll(t, text, "error *", match.ScanContext(3, 3)))
+
+	// the context behind the last selected line still gets returned
+	assert.Equal(t, []string{"2:error disk", "3-info a"}, scanAll(t, text, "error *", match.ScanMaxCount(1), match.ScanContext(0, 1)))
+
+	assert.Equal(t, []string{"2:error", "5:error"}, scanAll(t, "info error info disk error", "error", match.ScanSplit(bufio.ScanWords)))
+}
+
+func TestScannerAllocations(t *testing.T) {
+	m, err := match.Compile("[ warn | error ] *")
+	assert.NoError(t, err)
+	text := strings.Repeat("info starting worker\nerror worker crashed\ninfo restarting\n", 1000)
+	s := match.NewScanner(strings.NewReader(text), m, match.ScanContext(2, 1))
+	allocs := testing.AllocsPerRun(500, func() {
+		s.Scan()
+	})
+	assert.Equal(t, 0.0, allocs)
+
+	// repetitions reuse the states of their automaton and combinations of compiled matchers get no copies
+	text = strings.Repeat("abba error\nerror crashed\nbab\n", 1000)
+	repeated, err := match.Compile("[ a | b ]+ *")
+	assert.NoError(t, err)
+	expr, err := match.CompileExpr("error* && !*crashed")
+	assert.NoError(t, err)
+	for _, m := range []match.Matcher{repeated, expr, match.Not(m)} {
+		s = match.NewScanner(strings.NewReader(text), m, match.ScanContext(2, 1))
+		allocs = testing.AllocsPerRun(500, func() {
+			s.Scan()
+		})
+		assert.Equal(t, 0.0, allocs)
+	}
+}
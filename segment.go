@@ -0,0 +1,168 @@
+package match
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// segmentMarkerBase is the first rune of the supplementary private use area,
+// a group that can not be expanded into the cartesian product is replaced by a marker
+// rune starting from this base and matched by its segment instead
+const segmentMarkerBase = 0xF0000
+
+// segment matches a part of the data that can not be expressed as a wildcard pattern
+type segment interface {
+	matches(data string) bool
+}
+
+// repeatSegment matches min to max repetitions of its alternatives, a max of -1 means unbounded
+type repeatSegment struct {
+	alternatives     []string
+	min              int
+	max              int
+	optionalQuestion bool
+	nfa              *nfa
+}
+
+func newRepeatSegment(alternatives []string, min int, max int, optionalQuestion bool) *repeatSegment {
+	return &repeatSegment{
+		alternatives:     alternatives,
+		min:              min,
+		max:              max,
+		optionalQuestion: optionalQuestion,
+		nfa:              compileRepetitionNFA(alternatives, min, max, optionalQuestion),
+	}
+}
+
+func (s *repeatSegment) matches(data string) bool {
+	return s.nfa.matches(data)
+}
+
+// regexpOpen starts a regular expression inside a group, it ends with the next unescaped '/'
+const regexpOpen = "/re:"
+
+// regexpSegment matches data that is fully matched by its regular expression
+type regexpSegment struct {
+	source string
+	re     *regexp.Regexp
+}
+
+func newRegexpSegment(source string) (*regexpSegment, error) {
+	re, err := regexp.Compile(`^(?:` + strings.ReplaceAll(source, `\/`, "/") + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	return &regexpSegment{
+		source: source,
+		re:     re,
+	}, nil
+}
+
+func (s *regexpSegment) matches(data string) bool {
+	return s.re.MatchString(data)
+}
+
+// regexpEnd returns the index after the closing '/' of the regular expression starting at start,
+// or -1 if the regular expression is not closed
+func regexpEnd(s string, start int) int {
+	for i := start + len(regexpOpen); i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// rangeSegment matches a number between min and max.
+// If width is set the digits have to be zero padded to exactly that width,
+// otherwise the number must not have leading zeros
+type rangeSegment struct {
+	min   int64
+	max   int64
+	width int
+}
+
+var rangePattern = regexp.MustCompile(`^(-?)([0-9]+)\.\.(-?)([0-9]+)$`)
+
+// parseRange parses an alternative like 0..127, -10..10 or 000..127 into a range segment,
+// the returned bool reports if the alternative is a range at all
+func parseRange(alternative string) (*rangeSegment, bool, error) {
+	bounds := rangePattern.FindStringSubmatch(alternative)
+	if bounds == nil {
+		return nil, false, nil
+	}
+	min, err := strconv.ParseInt(bounds[1]+bounds[2], 10, 64)
+	if err != nil {
+		return nil, true, errors.New("invalid query: invalid minimum in range " + alternative)
+	}
+	max, err := strconv.ParseInt(bounds[3]+bounds[4], 10, 64)
+	if err != nil || max < min {
+		return nil, true, errors.New("invalid query: invalid maximum in range " + alternative)
+	}
+	seg := &rangeSegment{min: min, max: max}
+	if isZeroPadded(bounds[2]) || isZeroPadded(bounds[4]) {
+		seg.width = len(bounds[2])
+		if len(bounds[4]) > seg.width {
+			seg.width = len(bounds[4])
+		}
+	}
+	return seg, true, nil
+}
+
+func isZeroPadded(digits string) bool {
+	return len(digits) > 1 && digits[0] == '0'
+}
+
+func (s *rangeSegment) matches(data string) bool {
+	digits, negative := strings.CutPrefix(data, "-")
+	if digits == "" || (s.width != 0 && len(digits) != s.width) || (s.width == 0 && isZeroPadded(digits)) {
+		return false
+	}
+	// a sign is only allowed for negative numbers, so -0 never matches
+	if negative && (s.min >= 0 || strings.Trim(digits, "0") == "") {
+		return false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+	v, err := strconv.ParseInt(data, 10, 64)
+	return err == nil && v >= s.min && v <= s.max
+}
+
+func segmentMarker(index int) string {
+	return string(rune(segmentMarkerBase + index))
+}
+
+func isSegmentMarker(r rune) bool {
+	return r >= segmentMarkerBase && r <= utf8.MaxRune
+}
+
+func segmentIndex(r rune) int {
+	return int(r - segmentMarkerBase)
+}
+
+// indexSegmentMarker returns the index of the first segment marker in the pattern that is not escaped, or -1.
+// Runes of the marker range that were written in the pattern always get escaped by the parser
+func indexSegmentMarker(pattern string) int {
+	for i := 0; i < len(pattern); {
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		if r == '\\' {
+			_, escaped := utf8.DecodeRuneInString(pattern[i+size:])
+			i += size + escaped
+			continue
+		}
+		if isSegmentMarker(r) {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
@@ -0,0 +1,81 @@
+package match
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	seg, ok, err := parseRange("0..127")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &rangeSegment{min: 0, max: 127}, seg)
+
+	seg, ok, err = parseRange("-10..-2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &rangeSegment{min: -10, max: -2}, seg)
+
+	seg, ok, err = parseRange("000..127")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, &rangeSegment{min: 0, max: 127, width: 3}, seg)
+
+	_, ok, err = parseRange("a..b")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = parseRange("5..1")
+	assert.Error(t, err)
+	assert.True(t, ok)
+
+	_, ok, err = parseRange("0..99999999999999999999")
+	assert.Error(t, err)
+	assert.True(t, ok)
+}
+
+func TestRangeSegment(t *testing.T) {
+	testCases := []struct {
+		alternative string
+		data        string
+		matched     bool
+	}{
+		{alternative: "0..127", data: "0", matched: true},
+		{alternative: "0..127", data: "127", matched: true},
+		{alternative: "0..127", data: "128", matched: false},
+		{alternative: "0..127", data: "007", matched: false},
+		{alternative: "0..127", data: "", matched: false},
+		{alternative: "0..127", data: "-", matched: false},
+		{alternative: "0..127", data: "1a", matched: false},
+		{alternative: "0..127", data: "+1", matched: false},
+		{alternative: "000..127", data: "007", matched: true},
+		{alternative: "000..127", data: "7", matched: false},
+		{alternative: "000..127", data: "0127", matched: false},
+		{alternative: "-10..10", data: "-10", matched: true},
+		{alternative: "-10..10", data: "-11", matched: false},
+		{alternative: "-10..10", data: "-0", matched: false},
+		{alternative: "-10..10", data: "-00", matched: false},
+		{alternative: "0..127", data: "-0", matched: false},
+		{alternative: "0..127", data: "-5", matched: false},
+		{alternative: "-010..010", data: "-000", matched: false},
+		{alternative: "-010..010", data: "-005", matched: true},
+		{alternative: "-10..10", data: "--1", matched: false},
+	}
+	for _, testCase := range testCases {
+		seg, _, err := parseRange(testCase.alternative)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.matched, seg.matches(testCase.data), testCase.alternative+" "+testCase.data)
+	}
+}
+
+func TestRegexpSegment(t *testing.T) {
+	seg, err := newRegexpSegment(`[0-9]+|a\/b`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, seg.matches("123"))
+	assert.Equal(t, true, seg.matches("a/b"))
+	assert.Equal(t, false, seg.matches("123a/b"))
+
+	assert.Equal(t, 9, regexpEnd(`/re:a\/b/c`, 0))
+	assert.Equal(t, -1, regexpEnd(`/re:a\/b`, 0))
+}
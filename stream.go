@@ -0,0 +1,120 @@
+package match
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Stream matches data that arrives in chunks without buffering it, the pattern gets compiled
+// into an automaton that consumes the data rune by rune.
+// It implements io.Writer, after all data was written Matched reports if the data matched as a whole
+type Stream struct {
+	nfa     *nfa
+	current *stateSet
+	next    *stateSet
+
+	// pending holds the start of a rune that was split between two writes
+	pending  [utf8.UTFMax]byte
+	npending int
+}
+
+// NewStream returns a stream for a matcher returned by Compile or a merge of those by Or.
+// Patterns with exclusions, regular expressions, ranges, placeholders or predicates return ErrNotStreamable
+func NewStream(m Matcher) (*Stream, error) {
+	n, err := compileMatcherNFA(m)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{
+		nfa:     n,
+		current: newStateSet(len(n.insts)),
+		next:    newStateSet(len(n.insts)),
+	}
+	s.Reset()
+	return s, nil
+}
+
+// MatchReader reports if m matches all runes of the reader, the runes get matched while they are read.
+// Reading stops early as soon as no more runes can lead to a match
+func MatchReader(m Matcher, r io.RuneReader) (bool, error) {
+	n, err := compileMatcherNFA(m)
+	if err != nil {
+		return false, err
+	}
+	current := newStateSet(len(n.insts))
+	next := newStateSet(len(n.insts))
+	n.add(current, 0)
+	for len(current.dense) > 0 {
+		c, _, err := r.ReadRune()
+		if err == io.EOF {
+			return n.accepts(current), nil
+		}
+		if err != nil {
+			return false, err
+		}
+		n.step(current, next, c)
+		current, next = next, current
+	}
+	return false, nil
+}
+
+func compileMatcherNFA(m Matcher) (*nfa, error) {
+	cm, ok := m.(matcher)
+	if !ok {
+		return nil, ErrNotStreamable
+	}
+	return compilePreparedNFA(cm.prepared)
+}
+
+// Reset discards everything that was written, so the stream can be reused for other data
+func (s *Stream) Reset() {
+	s.current.clear()
+	s.npending = 0
+	s.nfa.add(s.current, 0)
+}
+
+// Write consumes the data, it never returns an error
+func (s *Stream) Write(p []byte) (int, error) {
+	n := len(p)
+	for s.npending > 0 {
+		if !utf8.FullRune(s.pending[:s.npending]) {
+			if len(p) == 0 {
+				return n, nil
+			}
+			s.pending[s.npending] = p[0]
+			s.npending++
+			p = p[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(s.pending[:s.npending])
+		s.consume(r)
+		s.npending = copy(s.pending[:], s.pending[size:s.npending])
+	}
+	for len(p) > 0 {
+		if !utf8.FullRune(p) {
+			s.npending = copy(s.pending[:], p)
+			break
+		}
+		r, size := utf8.DecodeRune(p)
+		s.consume(r)
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// Matched reports if everything written since the last reset is matched by the pattern.
+// It ends the data, a rune that is still incomplete counts as invalid bytes
+func (s *Stream) Matched() bool {
+	for ; s.npending > 0; s.npending-- {
+		s.consume(utf8.RuneError)
+	}
+	return s.nfa.accepts(s.current)
+}
+
+func (s *Stream) consume(r rune) {
+	if len(s.current.dense) == 0 {
+		return
+	}
+	s.nfa.step(s.current, s.next, r)
+	s.current, s.next = s.next, s.current
+}
@@ -0,0 +1,82 @@
+package match_test
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	patterns := []string{
+		"svc.[ api | web ].*",
+		"[:id *].log",
+		"a?[ b | cd ]+e",
+		`\*+ä`,
+		"logs.* | metrics.*",
+		"??",
+	}
+	inputs := []string{"svc.api.requests", "svc.db.x", "x.log", "a.log.gz", "axbcdbe", "abe", "axe", `*xyzä`, "*ä", "metrics.cpu", "", "ä", "\xff\xe2", "\xe2\x82"}
+	for _, pattern := range patterns {
+		m, err := match.Compile(pattern)
+		assert.NoError(t, err)
+		s, err := match.NewStream(m)
+		assert.NoError(t, err)
+		for _, input := range inputs {
+			// write the input in chunks that split multi byte runes
+			s.Reset()
+			data := []byte(input)
+			for len(data) > 0 {
+				n := 1 + len(data)%3
+				if n > len(data) {
+					n = len(data)
+				}
+				s.Write(data[:n])
+				data = data[n:]
+			}
+			assert.Equal(t, m.Matches(input), s.Matched(), pattern+" "+input)
+
+			matched, err := match.MatchReader(m, strings.NewReader(input))
+			assert.NoError(t, err)
+			assert.Equal(t, m.Matches(input), matched, pattern+" "+input)
+		}
+	}
+}
+
+func TestStreamLargeInput(t *testing.T) {
+	m, err := match.Compile("{*}")
+	assert.NoError(t, err)
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte("{"))
+		for i := 0; i < 1000; i++ {
+			w.Write([]byte(strings.Repeat("x", 1000)))
+		}
+		w.Write([]byte("}"))
+		w.Close()
+	}()
+	matched, err := match.MatchReader(m, bufio.NewReader(r))
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	// reading stops as soon as the data can not match anymore
+	reader := strings.NewReader("[" + strings.Repeat("x", 100))
+	matched, err = match.MatchReader(m, reader)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+	assert.Equal(t, 100, reader.Len())
+}
+
+func TestStreamNotStreamable(t *testing.T) {
+	for _, pattern := range []string{"id.[ :int ]", "logs.[ * ! debug ]", "[ /re:a+/ ]"} {
+		m, err := match.Compile(pattern)
+		assert.NoError(t, err)
+		_, err = match.NewStream(m)
+		assert.ErrorIs(t, err, match.ErrNotStreamable)
+	}
+	_, err := match.NewStream(match.Not(match.Or()))
+	assert.ErrorIs(t, err, match.ErrNotStreamable)
+}
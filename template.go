@@ -0,0 +1,47 @@
+package match
+
+import (
+	"strings"
+)
+
+// CompileTemplate replaces every ${name} in the template with the quoted value of the variable
+// and compiles the result, so the values are always matched literally
+//
+// # A template can look like the following:
+//
+// tenants.${tenant}.[ * ]
+func CompileTemplate(template string, vars map[string]string, opts ...Option) (Matcher, error) {
+	pattern, err := interpolate(template, vars)
+	if err != nil {
+		return matcher{}, err
+	}
+	return Compile(pattern, opts...)
+}
+
+// interpolate replaces the variables of the template with their quoted values
+func interpolate(template string, vars map[string]string) (string, error) {
+	interpolated := strings.Builder{}
+	for i := 0; i < len(template); i++ {
+		if template[i] == '\\' && i+1 < len(template) {
+			interpolated.WriteString(template[i : i+2])
+			i++
+			continue
+		}
+		if !strings.HasPrefix(template[i:], "${") {
+			interpolated.WriteByte(template[i])
+			continue
+		}
+		end := strings.IndexByte(template[i:], '}')
+		if end == -1 {
+			return "", &PatternError{Pos: i, Msg: "expected '}' after '${'"}
+		}
+		name := template[i+2 : i+end]
+		value, ok := vars[name]
+		if !ok {
+			return "", &PatternError{Pos: i, Msg: "unknown variable " + name}
+		}
+		interpolated.WriteString(QuoteMeta(value))
+		i += end
+	}
+	return interpolated.String(), nil
+}
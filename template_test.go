@@ -0,0 +1,67 @@
+package match_test
+
+import (
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteMeta(t *testing.T) {
+	for _, text := range []string{"acme", "a*b", "[ x | y ]", "!x", "@tenant", ":int", "1..5", `back\slash`, " padded ", "/re:.*/", "a && (b)", "ünï*cöde", "{2}", "a{1,3}"} {
+		m, err := match.Compile(match.QuoteMeta(text))
+		assert.NoError(t, err, text)
+		assert.Equal(t, true, m.Matches(text), text)
+		assert.Equal(t, false, m.Matches(text+"x"), text)
+		assert.Equal(t, false, m.Matches("x"+text), text)
+
+		m, err = match.Compile("[ " + match.QuoteMeta(text) + " | other ].*")
+		assert.NoError(t, err, text)
+		assert.Equal(t, true, m.Matches(text+".log"), text)
+		assert.Equal(t, false, m.Matches("x.log"), text)
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	m, err := match.Compile(`a\*[ \| | \] ].*`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("a*|."))
+	assert.Equal(t, true, m.Matches("a*].xyz"))
+	assert.Equal(t, false, m.Matches("ab|."))
+
+	m, err = match.Compile(`[ a\?b ]+`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("a?ba?b"))
+	assert.Equal(t, false, m.Matches("axb"))
+
+	_, err = match.Compile(`abc\`)
+	assert.Error(t, err)
+}
+
+func TestCompileTemplate(t *testing.T) {
+	m, err := match.CompileTemplate("tenants.${tenant}.[ * ]", map[string]string{"tenant": "a*|b"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("tenants.a*|b.users"))
+	assert.Equal(t, false, m.Matches("tenants.abc.users"))
+	assert.Equal(t, false, m.Matches("tenants.a.users"))
+
+	m, err = match.CompileTemplate("${env}-[ ${a} | ${b} ]", map[string]string{"env": "prod", "a": "x]", "b": "1..3"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("prod-x]"))
+	assert.Equal(t, true, m.Matches("prod-1..3"))
+	assert.Equal(t, false, m.Matches("prod-2"))
+
+	// a value right after a group must not turn into a quantifier
+	m, err = match.CompileTemplate("[ a ]${v}", map[string]string{"v": "{2}"})
+	assert.NoError(t, err)
+	assert.Equal(t, true, m.Matches("a{2}"))
+	assert.Equal(t, false, m.Matches("aa"))
+
+	_, err = match.CompileTemplate("tenants.${missing}", map[string]string{})
+	patternErr, ok := err.(*match.PatternError)
+	assert.True(t, ok)
+	assert.Equal(t, 8, patternErr.Pos)
+
+	_, err = match.CompileTemplate("tenants.${tenant", map[string]string{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package match
+
+import (
+	"errors"
+	"strings"
+)
+
+// TokenPattern matches sequences of tokens like the components of a path or the segments of a dotted name.
+// Every element of the pattern matches a whole token, * matches exactly one token and ** any number of tokens
+type TokenPattern struct {
+	elements []tokenElement
+}
+
+type tokenElement struct {
+	// one is set for *, the element matches any single token
+	one bool
+	// many is set for **, the element matches any number of tokens
+	many bool
+	// m matches string tokens
+	m Matcher
+	// alternatives are the unescaped alternatives of the element, they get compared by the equality function of MatchTokensFunc
+	alternatives []string
+}
+
+// CompileTokens splits the pattern at every separator outside of groups and compiles its elements
+//
+// # A token pattern with the separator . can look like the following:
+//
+// users.[ * | admin ].**.profile
+//
+// The elements are patterns as accepted by Compile, so users.[ * | admin ].profile matches
+// the tokens users, 42, profile. The options get applied to every element.
+// MatchTokensFunc compares the tokens with the literal alternatives of the elements, so elements with
+// regular expressions, ranges, placeholders, predicates, captures, exclusions or unbounded repetitions return an error
+func CompileTokens(pattern string, sep string, opts ...Option) (*TokenPattern, error) {
+	if sep == "" {
+		return nil, errors.New("invalid token pattern: empty separator")
+	}
+	cfg := newConfig(opts)
+	p := &TokenPattern{}
+	for _, element := range splitOutsideGroups(pattern, sep) {
+		element = cfg.trim(element)
+		switch strings.TrimSpace(element) {
+		case "**":
+			p.elements = append(p.elements, tokenElement{many: true})
+			continue
+		case "*", "[ * ]", "[*]":
+			p.elements = append(p.elements, tokenElement{one: true})
+			continue
+		}
+		m, err := Compile(element, opts...)
+		if err != nil {
+			return nil, err
+		}
+		alternatives, err := tokenAlternatives(element, cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.elements = append(p.elements, tokenElement{m: m, alternatives: alternatives})
+	}
+	return p, nil
+}
+
+// tokenAlternatives expands the groups of an element into its literal alternatives,
+// svc-[ a | b ] has the alternatives svc-a and svc-b. Wildcards stay in the alternatives as they are
+func tokenAlternatives(element string, cfg config) ([]string, error) {
+	if strings.HasPrefix(element, extendedFlag) {
+		cfg.extended = true
+		element = element[len(extendedFlag):]
+	}
+	if cfg.extended {
+		element, _ = stripExtended(element)
+	}
+	if cfg.library != nil {
+		expanded, _, err := cfg.library.expand(element)
+		if err != nil {
+			return nil, err
+		}
+		element = expanded
+	}
+	alternatives := []string{}
+	for _, alternative := range splitOutsideGroups(element, "|") {
+		parts, err := parseQueryIntoParts(cfg.trim(alternative), cfg)
+		if err != nil {
+			return nil, err
+		}
+		parts, err = parsePatterns(parts, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			if p.segments != nil || p.exclusions != nil {
+				return nil, errors.New("invalid token pattern: element " + element + " has no literal alternatives")
+			}
+		}
+		for _, product := range generateCartesianProduct(parts) {
+			alternatives = append(alternatives, unescape(strings.Join(product, "")))
+		}
+	}
+	return alternatives, nil
+}
+
+// Matches reports if the pattern matches the whole sequence of tokens
+func (p *TokenPattern) Matches(tokens []string) bool {
+	return matchTokens(p.elements, tokens, func(e tokenElement, token string) bool {
+		return e.m.Matches(token)
+	})
+}
+
+// MatchTokensFunc matches a sequence of tokens of any type.
+// A token matches an element if eq reports it as equal to one of the alternatives of the element,
+// the alternatives are compared literally, only * and ** match any token
+func MatchTokensFunc[T any](p *TokenPattern, tokens []T, eq func(alternative string, token T) bool) bool {
+	return matchTokens(p.elements, tokens, func(e tokenElement, token T) bool {
+		for _, alternative := range e.alternatives {
+			if eq(alternative, token) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// matchTokens works like the wildcard matching, but on tokens instead of runes
+func matchTokens[T any](elements []tokenElement, tokens []T, match func(tokenElement, T) bool) bool {
+	for len(elements) > 0 {
+		e := elements[0]
+		if e.many {
+			for i := 0; i <= len(tokens); i++ {
+				if matchTokens(elements[1:], tokens[i:], match) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(tokens) == 0 || (!e.one && !match(e, tokens[0])) {
+			return false
+		}
+		elements = elements[1:]
+		tokens = tokens[1:]
+	}
+	return len(tokens) == 0
+}
@@ -0,0 +1,71 @@
+package match_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Instantan/match"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenPattern(t *testing.T) {
+	p, err := match.CompileTokens("users.[ * | admin ].**.[ profile | settings ]", ".")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, p.Matches([]string{"users", "42", "profile"}))
+	assert.Equal(t, true, p.Matches([]string{"users", "42", "a", "b.c", "settings"}))
+	assert.Equal(t, true, p.Matches([]string{"users", "a.b", "settings"}))
+	assert.Equal(t, false, p.Matches([]string{"users", "profile"}))
+	assert.Equal(t, false, p.Matches([]string{"groups", "42", "profile"}))
+
+	p, err = match.CompileTokens("src/**/*.go", "/")
+	assert.NoError(t, err)
+	assert.Equal(t, true, p.Matches(strings.Split("src/main.go", "/")))
+	assert.Equal(t, true, p.Matches(strings.Split("src/a/b/c.go", "/")))
+	assert.Equal(t, false, p.Matches(strings.Split("src/a/b/c.rs", "/")))
+	assert.Equal(t, false, p.Matches(strings.Split("lib/main.go", "/")))
+
+	p, err = match.CompileTokens("a.[ b.c | d ]", ".")
+	assert.NoError(t, err)
+	assert.Equal(t, true, p.Matches([]string{"a", "b.c"}))
+	assert.Equal(t, false, p.Matches([]string{"a", "b", "c"}))
+
+	_, err = match.CompileTokens("a", "")
+	assert.Error(t, err)
+}
+
+func TestMatchTokensFunc(t *testing.T) {
+	type token struct {
+		text string
+	}
+	eq := func(alternative string, t token) bool {
+		return strings.EqualFold(alternative, t.text)
+	}
+	p, err := match.CompileTokens("GET / [ users | groups ] / * / **", "/")
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, match.MatchTokensFunc(p, []token{{"get"}, {"USERS"}, {"42"}}, eq))
+	assert.Equal(t, true, match.MatchTokensFunc(p, []token{{"GET"}, {"groups"}, {"1"}, {"members"}, {"2"}}, eq))
+	assert.Equal(t, false, match.MatchTokensFunc(p, []token{{"POST"}, {"users"}, {"42"}}, eq))
+	assert.Equal(t, false, match.MatchTokensFunc(p, []token{{"GET"}, {"users"}}, eq))
+
+	// groups apply per token, also next to static text
+	p, err = match.CompileTokens(`svc-[ a | b ]{1,2}.x | "y z"`, ".")
+	assert.NoError(t, err)
+	for _, tokens := range [][]string{{"svc-a", "x"}, {"svc-ab", "x"}, {"svc-a", "y z"}, {"svc-c", "x"}, {"svc-a", "x | y z"}} {
+		assert.Equal(t, p.Matches(tokens), match.MatchTokensFunc(p, tokens, func(alternative string, token string) bool {
+			return alternative == token
+		}), tokens)
+	}
+
+	// the options apply to the alternatives too
+	p, err = match.CompileTokens("[a|b]- ", ".", match.WithoutTrimming())
+	assert.NoError(t, err)
+	assert.Equal(t, true, match.MatchTokensFunc(p, []token{{"B- "}}, eq))
+	assert.Equal(t, false, match.MatchTokensFunc(p, []token{{"B-"}}, eq))
+
+	for _, pattern := range []string{"a.[ /re:[0-9]+/ ]", "a.[:int]", "a.[ * ! b ]", "a.[ b ]+"} {
+		_, err = match.CompileTokens(pattern, ".")
+		assert.Error(t, err, pattern)
+	}
+}
@@ -1,5 +1,28 @@
 package match
 
+import "unicode/utf8"
+
+// # The wildcards behave the same in every matching path:
+//
+// ? matches exactly one rune, or zero or one rune if compiled WithOptionalQuestionMark
+//
+// * matches zero or more runes
+//
+// + matches one or more runes
+//
+// \ matches the rune after it literally
+
+// wildcardMatch holds everything a pattern needs to be matched besides the pattern itself
+type wildcardMatch struct {
+	optionalQuestion bool
+	segments         []segment
+
+	// captures records the byte offsets of the capture boundaries, it is only set if captures are wanted
+	captures []int
+	// length is the length of the whole data, it is used to calculate the offsets of the captures
+	length int
+}
+
 func matchWildcardSimple(pattern, data string) bool {
 	if pattern == "" {
 		return data == pattern
@@ -7,7 +30,8 @@ func matchWildcardSimple(pattern, data string) bool {
 	if pattern == "*" {
 		return true
 	}
-	return deepMatch([]rune(data), []rune(pattern), true)
+	w := wildcardMatch{}
+	return w.deepMatch(data, pattern)
 }
 
 func matchWildcardAdvanced(pattern, data string) (matched bool) {
@@ -17,26 +41,113 @@ func matchWildcardAdvanced(pattern, data string) (matched bool) {
 	if pattern == "*" {
 		return true
 	}
-	return deepMatch([]rune(data), []rune(pattern), false)
+	w := wildcardMatch{}
+	return w.deepMatch(data, pattern)
+}
+
+// matchWildcard matches a pattern that needs the options of its prepared,
+// every segment marker consumes the part of the data its segment accepts
+func matchWildcard(pattern, data string, optionalQuestion bool, segments []segment) bool {
+	w := wildcardMatch{optionalQuestion: optionalQuestion, segments: segments}
+	return w.deepMatch(data, pattern)
 }
 
-func deepMatch(str, pattern []rune, simple bool) bool {
+// deepMatch works on the strings directly and decodes their runes while it goes,
+// so matching does not allocate
+func (w *wildcardMatch) deepMatch(str, pattern string) bool {
 	for len(pattern) > 0 {
-		switch pattern[0] {
+		p, size := utf8.DecodeRuneInString(pattern)
+		switch p {
 		default:
-			if len(str) == 0 || str[0] != pattern[0] {
+			// markers without a segment can only come from decoded data, they get compared literally
+			if w.segments != nil && isSegmentMarker(p) && segmentIndex(p) < len(w.segments) {
+				return w.deepMatchSegment(str, pattern, size)
+			}
+		case '\\':
+			// the escaped rune gets compared literally
+			if len(pattern) == 1 {
 				return false
 			}
+			pattern = pattern[size:]
+			p, size = utf8.DecodeRuneInString(pattern)
 		case '?':
-			if len(str) == 0 && !simple {
+			if w.optionalQuestion {
+				return w.deepMatch(str, pattern[size:]) ||
+					(len(str) > 0 && w.deepMatch(str[runeLen(str):], pattern[size:]))
+			}
+			if len(str) == 0 {
 				return false
 			}
+			str = str[runeLen(str):]
+			pattern = pattern[size:]
+			continue
 		case '*':
-			return deepMatch(str, pattern[1:], simple) ||
-				(len(str) > 0 && deepMatch(str[1:], pattern, simple))
+			return w.deepMatchStar(str, pattern[size:], 0)
+		case '+':
+			return w.deepMatchStar(str, pattern[size:], 1)
+		}
+		r, n := utf8.DecodeRuneInString(str)
+		if len(str) == 0 || r != p {
+			return false
+		}
+		str = str[n:]
+		pattern = pattern[size:]
+	}
+	return len(str) == 0
+}
+
+// deepMatchStar lets a star consume at least min runes and tries to match the rest
+// of the pattern after every amount of consumed runes
+func (w *wildcardMatch) deepMatchStar(str, pattern string, min int) bool {
+	for ; min > 0; min-- {
+		if len(str) == 0 {
+			return false
 		}
-		str = str[1:]
-		pattern = pattern[1:]
+		str = str[runeLen(str):]
 	}
-	return len(str) == 0 && len(pattern) == 0
+	for {
+		if w.deepMatch(str, pattern) {
+			return true
+		}
+		if len(str) == 0 {
+			return false
+		}
+		str = str[runeLen(str):]
+	}
+}
+
+// deepMatchSegment tries every split of the data where the segment at the start of the pattern
+// accepts the first part and the rest of the pattern the remaining data. Capture boundaries are zero width
+func (w *wildcardMatch) deepMatchSegment(str, pattern string, size int) bool {
+	marker, _ := utf8.DecodeRuneInString(pattern)
+	seg := w.segments[segmentIndex(marker)]
+	if boundary, ok := seg.(*captureSegment); ok {
+		if w.captures != nil {
+			w.captures[boundary.slot()] = w.length - len(str)
+		}
+		return w.deepMatch(str, pattern[size:])
+	}
+	if len(pattern) == size {
+		return seg.matches(str)
+	}
+	// predicates are user code, so they only get called at the splits where the rest matches
+	_, predicate := seg.(*predicateSegment)
+	for end := 0; ; end += runeLen(str[end:]) {
+		if predicate {
+			if w.deepMatch(str[end:], pattern[size:]) && seg.matches(str[:end]) {
+				return true
+			}
+		} else if seg.matches(str[:end]) && w.deepMatch(str[end:], pattern[size:]) {
+			return true
+		}
+		if end == len(str) {
+			return false
+		}
+	}
+}
+
+// runeLen returns the length of the first rune of s
+func runeLen(s string) int {
+	_, size := utf8.DecodeRuneInString(s)
+	return size
 }
@@ -396,3 +396,48 @@ func TestMatchWildcardSimple(t *testing.T) {
 		assert.Equal(t, testCase.matched, actualResult, "Test %v failed: pattern=%v, text=%v", i+1, testCase.pattern, testCase.text)
 	}
 }
+
+func TestWildcardSemantics(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		text     string
+		exact    bool
+		optional bool
+	}{
+		{pattern: "a?c", text: "abc", exact: true, optional: true},
+		{pattern: "a?c", text: "ac", exact: false, optional: true},
+		{pattern: "a?c", text: "abbc", exact: false, optional: false},
+		{pattern: "ab?", text: "ab", exact: false, optional: true},
+		{pattern: "?", text: "", exact: false, optional: true},
+		{pattern: "??", text: "x", exact: false, optional: true},
+		{pattern: "a+c", text: "ac", exact: false, optional: false},
+		{pattern: "a+c", text: "abc", exact: true, optional: true},
+		{pattern: "a+c", text: "abbbc", exact: true, optional: true},
+		{pattern: "+", text: "", exact: false, optional: false},
+		{pattern: "+", text: "x", exact: true, optional: true},
+		{pattern: "a*c", text: "ac", exact: true, optional: true},
+		{pattern: "?+", text: "x", exact: false, optional: true},
+		{pattern: "h?llo*", text: "hllo", exact: false, optional: true},
+		{pattern: "h?llo*", text: "hallo world", exact: true, optional: true},
+	}
+	for _, testCase := range testCases {
+		name := testCase.pattern + " " + testCase.text
+
+		assert.Equal(t, testCase.exact, matchWildcardSimple(testCase.pattern, testCase.text), name)
+		assert.Equal(t, testCase.exact, matchWildcardAdvanced(testCase.pattern, testCase.text), name)
+		assert.Equal(t, testCase.exact, matchWildcard(testCase.pattern, testCase.text, false, nil), name)
+		assert.Equal(t, testCase.optional, matchWildcard(testCase.pattern, testCase.text, true, nil), name)
+
+		// the terminator makes sure the repetition can only match once
+		assert.Equal(t, testCase.exact, compileRepetitionNFA([]string{testCase.pattern + "#"}, 1, -1, false).matches(testCase.text+"#"), name)
+		assert.Equal(t, testCase.optional, compileRepetitionNFA([]string{testCase.pattern + "#"}, 1, -1, true).matches(testCase.text+"#"), name)
+
+		m, err := Compile("pre[ " + testCase.pattern + " ]suf")
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.exact, m.Matches("pre"+testCase.text+"suf"), name)
+
+		m, err = Compile("pre[ "+testCase.pattern+" ]suf", WithOptionalQuestionMark())
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.optional, m.Matches("pre"+testCase.text+"suf"), name)
+	}
+}